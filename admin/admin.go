@@ -0,0 +1,178 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"notion-summary/kimi"
+	"notion-summary/notion"
+	notionAPI "notion-summary/notion/api"
+	"notion-summary/store"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultRunsLimit is how many runs GET /runs returns without a limit parameter.
+const defaultRunsLimit = 20
+
+// Server is the admin API, every endpoint requires the bearer token.
+type Server struct {
+	token  string
+	client *notionAPI.Client
+	ready  atomic.Bool
+	// runCtx outlives the requests, syncs started through the API run with it.
+	runCtx context.Context
+}
+
+func NewServer(runCtx context.Context, token string, client *notionAPI.Client) *Server {
+	return &Server{token: token, client: client, runCtx: runCtx}
+}
+
+// SetReady sets what /readyz reports: ready once the jobs are scheduled, not
+// ready again while shutting down.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Handler routes the admin endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	mux.HandleFunc("POST /sync", s.sync)
+	mux.HandleFunc("GET /runs", s.listRuns)
+	mux.HandleFunc("GET /runs/{id}", s.getRun)
+	mux.HandleFunc("POST /summarize", s.summarize)
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return s.authorize(mux)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// sync starts a sync in the background and answers with its run.
+func (s *Server) sync(w http.ResponseWriter, r *http.Request) {
+	run, err := notion.StartSync(s.runCtx, s.client, notion.SyncOptions{Trigger: "api"})
+	switch {
+	case errors.Is(err, notion.ErrSyncRunning):
+		writeError(w, http.StatusConflict, err)
+		return
+	case errors.Is(err, notion.ErrShuttingDown):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	limit := defaultRunsLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = n
+	}
+
+	runs, err := store.Default.ListRuns(limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request) {
+	run, err := store.Default.GetRun(r.PathValue("id"))
+	if errors.Is(err, store.ErrRunNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+type summarizeRequest struct {
+	URL   string `json:"url"`
+	Style string `json:"style"`
+}
+
+// summarize queues the url of the request body and answers with its run.
+func (s *Server) summarize(w http.ResponseWriter, r *http.Request) {
+	var req summarizeRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("body must be a JSON object with a url"))
+		return
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, errors.New("url must be an absolute http(s) url"))
+		return
+	}
+
+	style, err := kimi.ParseStyle(req.Style)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	run, err := notion.QueueSummarize(req.URL, style)
+	switch {
+	case errors.Is(err, notion.ErrQueueFull), errors.Is(err, notion.ErrQueueStopped), errors.Is(err, notion.ErrShuttingDown):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("write admin response error:%v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
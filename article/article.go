@@ -0,0 +1,125 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"notion-summary/config"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-readability"
+)
+
+const userAgent = "Mozilla/5.0 (compatible; notion-summary; +https://github.com/zhengweikeng/notion-summary)"
+
+var (
+	ErrNotHTML  = errors.New("not an html page")
+	ErrTooShort = errors.New("extracted content is too short")
+)
+
+// Text returns the readable content of the article at link as Markdown. It
+// falls back to the feed body when fetching is off or the extraction fails,
+// and returns an empty string when neither has any text.
+func Text(ctx context.Context, link, feedBody string) string {
+	if config.Article.Fetch && link != "" {
+		text, err := Extract(ctx, link)
+		if err == nil {
+			return text
+		}
+		log.Printf("extract %s error:%v, use the feed body\n", link, err)
+	}
+
+	text, err := Markdown(feedBody)
+	if err != nil {
+		log.Printf("convert feed body of %s error:%v\n", link, err)
+		return ""
+	}
+	return text
+}
+
+// Extract downloads the page at link and converts its main content, found the
+// way Firefox's reader view does, to Markdown.
+func Extract(ctx context.Context, link string) (string, error) {
+	pageURL, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	// Waiting for the host does not count against the fetch timeout.
+	release, err := AcquireHost(ctx, link)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.Article.TimeoutSeconds)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch article: %s", resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("%w: %s", ErrNotHTML, mediaType)
+	}
+
+	body := io.LimitReader(resp.Body, int64(config.Article.MaxBytes))
+	parsed, err := readability.FromReader(body, pageURL)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := Markdown(parsed.Content)
+	if err != nil {
+		return "", err
+	}
+	if utf8.RuneCountInString(text) < config.Article.MinLength {
+		return "", ErrTooShort
+	}
+	return text, nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// Markdown converts an HTML fragment to Markdown without images, which
+// carry no text for the model.
+func Markdown(html string) (string, error) {
+	if strings.TrimSpace(html) == "" {
+		return "", nil
+	}
+
+	converter := md.NewConverter("", true, nil)
+	converter.AddRules(md.Rule{
+		Filter: []string{"img", "picture", "svg", "script", "style", "iframe"},
+		Replacement: func(string, *goquery.Selection, *md.Options) *string {
+			return md.String("")
+		},
+	})
+	text, err := converter.ConvertString(html)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n")), nil
+}
@@ -0,0 +1,58 @@
+package article
+
+import (
+	"context"
+	"net/url"
+	"notion-summary/config"
+	"sync"
+)
+
+var (
+	hostsOnce sync.Once
+	hosts     *hostLimiter
+)
+
+// AcquireHost waits for a free slot of the host of rawURL, release frees it.
+// Feeds and articles share the slots, at most pipeline.fetch_per_host
+// requests go to a host at once. It returns ctx.Err() once ctx is done.
+func AcquireHost(ctx context.Context, rawURL string) (release func(), err error) {
+	hostsOnce.Do(func() {
+		hosts = newHostLimiter(max(config.Pipeline.FetchPerHost, 1))
+	})
+	return hosts.acquire(ctx, rawURL)
+}
+
+// hostLimiter bounds the concurrent requests to any single host.
+type hostLimiter struct {
+	limit int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, hosts: map[string]chan struct{}{}}
+}
+
+// acquire waits for a free slot of the host of rawURL, release frees it.
+func (l *hostLimiter) acquire(ctx context.Context, rawURL string) (release func(), err error) {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, l.limit)
+		l.hosts[host] = slots
+	}
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
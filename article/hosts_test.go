@@ -0,0 +1,30 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterCancel(t *testing.T) {
+	l := newHostLimiter(1)
+	release, err := l.acquire(context.Background(), "https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx, "https://example.com/b")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire() on a busy host error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	release()
+	release, err = l.acquire(context.Background(), "https://example.com/c")
+	if err != nil {
+		t.Fatalf("acquire() after release error = %v", err)
+	}
+	release()
+}
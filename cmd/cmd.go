@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"notion-summary/config"
+	"notion-summary/kimi"
+	"notion-summary/notion"
+	notionAPI "notion-summary/notion/api"
+	"notion-summary/store"
+	"os"
+	"strings"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "run the scheduled sync and the HTTP server (default)", runServe},
+		{"sync", "run a single sync and exit", runSync},
+		{"summarize", "summarize <url> and print the result without touching Notion", runSummarize},
+		{"feeds", "list|add <name> <url>|disable <id|name|url> subscriptions in the RSS database", runFeeds},
+		{"validate", "validate the config and the Notion database schemas", runValidate},
+		{"backfill", "sync every post published since --since, ignoring the watermarks", runBackfill},
+	}
+}
+
+// Execute runs the subcommand named by args[0]. Without a subcommand, or when
+// args starts with a flag, it serves as the binary always did.
+func Execute(args []string) error {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		printUsage()
+		return nil
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runServe(args)
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+
+	printUsage()
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: notion-summary <command> [--config config.yaml] [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.usage)
+	}
+}
+
+// newFlagSet returns the flags of a subcommand, every one accepts --config.
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to the YAML config file")
+	return fs, configPath
+}
+
+// setup loads the config, the summarizer and the state store, the returned
+// function closes what was opened.
+func setup(configPath string) (*notionAPI.Client, func(), error) {
+	log.Println("Initialize config")
+	err := config.InitConfig(configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	log.Println("Initialize summarizer")
+	err = kimi.InitSummarizer()
+	if err != nil {
+		return nil, nil, fmt.Errorf("InitSummarizer error:%w", err)
+	}
+
+	log.Println("Initialize state store")
+	err = store.Init(config.Store.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open state store error:%w", err)
+	}
+
+	return notion.NewClient(), func() { store.Default.Close() }, nil
+}
+
+var errInvalidSchema = errors.New("notion database schemas are invalid, fix them or set NOTION_SCHEMA_AUTOFIX=true")
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notion-summary/config"
+	"notion-summary/notion"
+	"os"
+	"text/tabwriter"
+)
+
+const feedsUsage = "usage: feeds [--config config.yaml] list|add <name> <url>|disable <id|name|url>"
+
+func runFeeds(args []string) error {
+	fs, configPath := newFlagSet("feeds")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New(feedsUsage)
+	}
+
+	err = config.InitConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+	ctx := context.Background()
+	client := notion.NewClient()
+
+	switch fs.Arg(0) {
+	case "list":
+		feeds, err := notion.ListFeeds(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tURL\tENABLED")
+		for _, feed := range feeds {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", feed.ID, feed.Name, feed.URL, feed.Enabled)
+		}
+		return w.Flush()
+	case "add":
+		if fs.NArg() != 3 {
+			return errors.New(feedsUsage)
+		}
+		id, err := notion.AddFeed(ctx, client, fs.Arg(1), fs.Arg(2))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added %s: %s (%s)\n", fs.Arg(1), fs.Arg(2), id)
+		return nil
+	case "disable":
+		if fs.NArg() != 2 {
+			return errors.New(feedsUsage)
+		}
+		feed, err := notion.DisableFeed(ctx, client, fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("disabled %s: %s (%s)\n", feed.Name, feed.URL, feed.ID)
+		return nil
+	default:
+		return errors.New(feedsUsage)
+	}
+}
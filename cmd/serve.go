@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"notion-summary/admin"
+	"notion-summary/config"
+	"notion-summary/notion"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func runServe(args []string) error {
+	fs, configPath := newFlagSet("serve")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	client, closeAll, err := setup(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	log.Println("Validate notion database schemas")
+	problems, err := notion.ValidateSchemas(context.Background(), client, config.Notion.AutoFixSchema)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			log.Println(p)
+		}
+		return errInvalidSchema
+	}
+
+	// runCtx is cancelled when the grace period of a shutdown is over.
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	defer cancelRuns()
+
+	adminServer := admin.NewServer(runCtx, config.Service.AdminToken, client)
+	handler := adminServer.Handler()
+	if config.Service.AdminToken == "" {
+		log.Println("ADMIN_TOKEN is not set, the admin API is off and /metrics is public")
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", promhttp.Handler())
+		handler = mux
+	}
+	notion.StartSummarizeQueue(runCtx, client)
+
+	server := &http.Server{Addr: ":" + config.Service.Port, Handler: handler}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	log.Println("Initialize cron jobs")
+	scheduler := notion.InitCronJobs(runCtx)
+	adminServer.SetReady(true)
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err = <-serveErr:
+	case <-signalCtx.Done():
+		log.Println("Shutting down...")
+	}
+
+	adminServer.SetReady(false)
+	cronStopped := scheduler.Stop()
+	drainRuns(cancelRuns)
+	select {
+	case <-cronStopped.Done():
+	case <-time.After(cronStopTimeout):
+		log.Println("cron jobs did not stop in time")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.Printf("shutdown http server error:%v\n", shutdownErr)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+const (
+	// drainTimeout is how long the runs in progress may keep going after a
+	// shutdown signal, they are cancelled afterwards.
+	drainTimeout = 20 * time.Second
+	// checkpointTimeout is how long cancelled runs get to stop at the next
+	// post, leaving the rest in the state store for the next sync.
+	checkpointTimeout = 5 * time.Second
+	// cronStopTimeout is how long the cron jobs get to return once the runs
+	// are drained or cancelled.
+	cronStopTimeout     = 5 * time.Second
+	httpShutdownTimeout = 5 * time.Second
+)
+
+// drainRuns waits for the syncs and summaries in progress, cancelling them
+// when they take longer than drainTimeout.
+func drainRuns(cancelRuns context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	err := notion.Drain(ctx)
+	if err == nil {
+		return
+	}
+
+	log.Println("Runs still in progress, stopping them at the next post")
+	cancelRuns()
+	ctx, cancel = context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+	err = notion.Drain(ctx)
+	if err != nil {
+		log.Printf("runs did not stop in time:%v\n", err)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notion-summary/article"
+	"notion-summary/config"
+	"notion-summary/kimi"
+)
+
+func runSummarize(args []string) error {
+	fs, configPath := newFlagSet("summarize")
+	asJSON := fs.Bool("json", false, "print the summary as JSON")
+	prompt := fs.String("prompt", "", "name of the prompt template, empty means the default one")
+	language := fs.String("language", "", "language of the summary, empty means ai.language")
+	styleName := fs.String("style", "", "summary style: tldr, bullets, detailed or technical, empty means ai.style")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: summarize [--config config.yaml] [--json] [--prompt name] [--language lang] [--style name] <url>")
+	}
+
+	style, err := kimi.ParseStyle(*styleName)
+	if err != nil {
+		return err
+	}
+
+	err = config.InitAIConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+	err = kimi.InitSummarizer()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	link := fs.Arg(0)
+	summary, err := kimi.DefaultSummarizer().Summarize(ctx, kimi.Article{
+		Link:     link,
+		Text:     article.Text(ctx, link, ""),
+		Template: *prompt,
+		Language: *language,
+		Style:    style,
+
+		Vocabulary: config.AI.Tags,
+		Profile:    kimi.ConfigProfile(config.Relevance),
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		fmt.Println(summary.JSON())
+		return nil
+	}
+	fmt.Print(summary.Markdown())
+	return nil
+}
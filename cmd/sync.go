@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"notion-summary/notion"
+	notionAPI "notion-summary/notion/api"
+	"notion-summary/store"
+	"time"
+)
+
+func runSync(args []string) error {
+	fs, configPath := newFlagSet("sync")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	client, closeAll, err := setupSync(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	_, err = notion.RunSync(context.Background(), client, notion.SyncOptions{Trigger: "cli"})
+	return err
+}
+
+func runBackfill(args []string) error {
+	fs, configPath := newFlagSet("backfill")
+	since := fs.String("since", "", "backfill posts published on or after this date, YYYY-MM-DD or RFC 3339")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *since == "" {
+		return errors.New("backfill requires --since")
+	}
+	sinceTime, err := time.Parse("2006-01-02", *since)
+	if err != nil {
+		sinceTime, err = time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return errors.New("--since must be YYYY-MM-DD or RFC 3339")
+		}
+	}
+
+	client, closeAll, err := setupSync(*configPath)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	_, err = notion.RunSync(context.Background(), client, notion.SyncOptions{Since: sinceTime, Trigger: "backfill"})
+	return err
+}
+
+// setupSync is setup for the commands that sync. A state store held by another
+// process means serve is up with the same STATE_DB_PATH, which syncs itself,
+// so the command fails the way a sync already running does.
+func setupSync(configPath string) (*notionAPI.Client, func(), error) {
+	client, closeAll, err := setup(configPath)
+	if errors.Is(err, store.ErrInUse) {
+		return nil, nil, fmt.Errorf("%w, use POST /sync of the running serve: %w", notion.ErrSyncRunning, err)
+	}
+	return client, closeAll, err
+}
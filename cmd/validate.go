@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"notion-summary/config"
+	"notion-summary/notion"
+)
+
+func runValidate(args []string) error {
+	fs, configPath := newFlagSet("validate")
+	fix := fs.Bool("fix", false, "add missing properties to the Notion databases")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = config.InitConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+	fmt.Println("config: ok")
+
+	problems, err := notion.ValidateSchemas(context.Background(), notion.NewClient(), *fix || config.Notion.AutoFixSchema)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		return errInvalidSchema
+	}
+
+	fmt.Println("notion schemas: ok")
+	return nil
+}
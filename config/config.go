@@ -1,50 +1,738 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
 )
 
 type ServiceConf struct {
-	Port             string
-	BlogSyncInterval string
+	Port             string `yaml:"port"`
+	BlogSyncInterval string `yaml:"sync_interval"`
+	MaxItemsPerFeed  int    `yaml:"max_items_per_feed"`
+	// AdminToken is the bearer token of the admin API, which is off when empty.
+	AdminToken string `yaml:"admin_token"`
 }
 
 type NotionConf struct {
-	NotionApiKey   string
-	NotionRssDBID  string
-	NotionPostDBID string
+	NotionApiKey      string `yaml:"api_key"`
+	NotionRssDBID     string `yaml:"rss_database_id"`
+	NotionPostDBID    string `yaml:"post_database_id"`
+	RequestsPerSecond int    `yaml:"requests_per_second"`
+	BaseURL           string `yaml:"base_url"`
+	Version           string `yaml:"version"`
+	TimeoutSeconds    int    `yaml:"timeout_seconds"`
+	AutoFixSchema     bool   `yaml:"auto_fix_schema"`
+	// PromptPageID is a page holding extra prompt templates, read before every sync.
+	PromptPageID string `yaml:"prompt_page_id"`
 }
 
 type AIConf struct {
-	KimiSecretKey string
-	KimiModel     string
+	Provider      string `yaml:"provider"`
+	KimiSecretKey string `yaml:"moonshot_api_key"`
+	KimiModel     string `yaml:"kimi_model"`
+	OpenAIBaseURL string `yaml:"openai_base_url"`
+	OpenAIAPIKey  string `yaml:"openai_api_key"`
+	OpenAIModel   string `yaml:"openai_model"`
+	OllamaBaseURL string `yaml:"ollama_base_url"`
+	OllamaModel   string `yaml:"ollama_model"`
+	// Models are the models a summary may use, the smallest one whose context
+	// fits the article is picked. Empty means the provider's defaults.
+	Models []ModelConf `yaml:"models"`
+	// MaxOutputTokens is kept free in the context for the answer.
+	MaxOutputTokens int `yaml:"max_output_tokens"`
+	// Language is the language of the summaries, a subscription may pick another one.
+	Language string `yaml:"language"`
+	// PromptDir holds prompt templates, every file is a template named after it.
+	PromptDir string `yaml:"prompt_dir"`
+	// Style is the summary style: tldr, bullets, detailed or technical.
+	Style string `yaml:"style"`
+	// Tags is the vocabulary the tags of a summary are picked from, empty
+	// with TagsFromNotion off means any tags.
+	Tags []string `yaml:"tags"`
+	// TagsFromNotion adds the options of the Post database's tags property to Tags.
+	TagsFromNotion bool `yaml:"tags_from_notion"`
+}
+
+type ModelConf struct {
+	Name          string `yaml:"name"`
+	ContextTokens int    `yaml:"context_tokens"`
+}
+
+// EmailConf configures the digest email sent with the newly summarized posts.
+// The digest is disabled while Recipients is empty, and is sent after every
+// sync unless DigestInterval schedules it on its own cron.
+type EmailConf struct {
+	Transport      string   `yaml:"transport"`
+	APIKey         string   `yaml:"resend_api_key"`
+	FROM           string   `yaml:"from"`
+	Recipients     []string `yaml:"recipients"`
+	Subject        string   `yaml:"subject"`
+	TemplatePath   string   `yaml:"template"`
+	DigestInterval string   `yaml:"digest_interval"`
+	SMTPHost       string   `yaml:"smtp_host"`
+	SMTPPort       int      `yaml:"smtp_port"`
+	SMTPUsername   string   `yaml:"smtp_username"`
+	SMTPPassword   string   `yaml:"smtp_password"`
+}
+
+// NotifierConf is one chat channel new posts are announced to. Subscriptions
+// limits the channel to the named subscriptions, an empty list means all of
+// them unless a subscription picks its channels in the RSS database.
+type NotifierConf struct {
+	Name          string   `yaml:"name"`
+	Type          string   `yaml:"type"`
+	WebhookURL    string   `yaml:"webhook_url"`
+	Secret        string   `yaml:"secret"`
+	BotToken      string   `yaml:"bot_token"`
+	ChatID        string   `yaml:"chat_id"`
+	Subscriptions []string `yaml:"subscriptions"`
+}
+
+// RSSPropertyConf maps the logical fields of the RSS database to Notion property names.
+type RSSPropertyConf struct {
+	Name          string `yaml:"name"`
+	URL           string `yaml:"url"`
+	Enabled       string `yaml:"enabled"`
+	MaxItems      string `yaml:"max_items"`
+	LastPublished string `yaml:"last_published"`
+	LastGUID      string `yaml:"last_guid"`
+	Notify        string `yaml:"notify"`
+	Prompt        string `yaml:"prompt"`
+	Language      string `yaml:"language"`
+	Style         string `yaml:"style"`
+}
+
+// PostPropertyConf maps the logical fields of the Post database to Notion property names.
+// Tags, Score and Style are optional, an empty name means the field is not written.
+type PostPropertyConf struct {
+	Name      string `yaml:"name"`
+	Authors   string `yaml:"authors"`
+	CNTitle   string `yaml:"cn_title"`
+	Published string `yaml:"published"`
+	Link      string `yaml:"link"`
+	Outline   string `yaml:"outline"`
+	Tags      string `yaml:"tags"`
+	Score     string `yaml:"score"`
+	Style     string `yaml:"style"`
+}
+
+type PropertyConf struct {
+	RSS  RSSPropertyConf  `yaml:"rss"`
+	Post PostPropertyConf `yaml:"post"`
+}
+
+type StoreConf struct {
+	Path        string `yaml:"path"`
+	MaxAttempts int    `yaml:"max_attempts"`
+	// LockPath is locked while a sync runs, so replicas sharing it never sync at once.
+	LockPath string `yaml:"lock_path"`
+}
+
+// PipelineConf bounds the workers of every sync stage.
+type PipelineConf struct {
+	FetchWorkers     int `yaml:"fetch_workers"`
+	FetchPerHost     int `yaml:"fetch_per_host"`
+	DedupeWorkers    int `yaml:"dedupe_workers"`
+	SummarizeWorkers int `yaml:"summarize_workers"`
+	WriteWorkers     int `yaml:"write_workers"`
+}
+
+// ArticleConf controls how the article text sent to the model is fetched.
+type ArticleConf struct {
+	// Fetch downloads the article and extracts its main content, otherwise
+	// only the feed body is used.
+	Fetch          bool `yaml:"fetch"`
+	TimeoutSeconds int  `yaml:"timeout_seconds"`
+	MaxBytes       int  `yaml:"max_bytes"`
+	// MinLength is the shortest extracted text, in characters, that is
+	// trusted over the feed body.
+	MinLength int `yaml:"min_length"`
+}
+
+// RelevanceConf is the team's interest profile posts are scored against. Posts
+// are only scored when the profile is not empty.
+type RelevanceConf struct {
+	Interests []string `yaml:"interests"`
+	Ignore    []string `yaml:"ignore"`
+	// ProfilePageID is a Notion page describing the interests in free text.
+	ProfilePageID string `yaml:"profile_page_id"`
+	// Threshold skips the summary of posts scored below it, 0 summarizes every post.
+	Threshold int `yaml:"threshold"`
+}
+
+// Config is the schema of the configuration file, every section can also be
+// set through environment variables, which take precedence over the file.
+type Config struct {
+	Service    ServiceConf    `yaml:"service"`
+	Notion     NotionConf     `yaml:"notion"`
+	AI         AIConf         `yaml:"ai"`
+	Email      EmailConf      `yaml:"email"`
+	Notifiers  []NotifierConf `yaml:"notifiers"`
+	Store      StoreConf      `yaml:"store"`
+	Pipeline   PipelineConf   `yaml:"pipeline"`
+	Article    ArticleConf    `yaml:"article"`
+	Relevance  RelevanceConf  `yaml:"relevance"`
+	Properties PropertyConf   `yaml:"properties"`
 }
 
 var Service ServiceConf
 var Notion NotionConf
 var AI AIConf
+var Email EmailConf
+var Notifiers []NotifierConf
+var Store StoreConf
+var Pipeline PipelineConf
+var Article ArticleConf
+var Relevance RelevanceConf
+var Properties PropertyConf
+
+func defaultConfig() Config {
+	return Config{
+		Service: ServiceConf{
+			Port:             "8080",
+			BlogSyncInterval: "@every 1h",
+			MaxItemsPerFeed:  10,
+		},
+		Notion: NotionConf{
+			RequestsPerSecond: 3,
+			BaseURL:           "https://api.notion.com",
+			Version:           "2022-06-28",
+			TimeoutSeconds:    30,
+		},
+		AI: AIConf{
+			Provider:      "moonshot",
+			KimiModel:     "moonshot-v1-32k",
+			OpenAIBaseURL: "https://api.openai.com/v1",
+			OpenAIModel:   "gpt-4o-mini",
+			OllamaBaseURL: "http://localhost:11434",
+			OllamaModel:   "qwen2.5",
+
+			MaxOutputTokens: 4096,
+			Language:        "中文",
+			Style:           "detailed",
+		},
+		Email: EmailConf{
+			Transport:    "resend",
+			Subject:      "Blog Updates",
+			TemplatePath: "template/blog.html",
+			SMTPPort:     25,
+		},
+		Store: StoreConf{
+			Path:        "data/state.db",
+			MaxAttempts: 3,
+			LockPath:    "data/sync.lock",
+		},
+		Pipeline: PipelineConf{
+			FetchWorkers:     16,
+			FetchPerHost:     2,
+			DedupeWorkers:    4,
+			SummarizeWorkers: 3,
+			WriteWorkers:     3,
+		},
+		Article: ArticleConf{
+			Fetch:          true,
+			TimeoutSeconds: 20,
+			MaxBytes:       5 << 20,
+			MinLength:      200,
+		},
+		Properties: PropertyConf{
+			RSS: RSSPropertyConf{
+				Name:          "Name",
+				URL:           "URL",
+				Enabled:       "Enabled",
+				MaxItems:      "Max Items",
+				LastPublished: "Last Published",
+				LastGUID:      "Last GUID",
+				Notify:        "Notify",
+				Prompt:        "Prompt",
+				Language:      "Language",
+				Style:         "Style",
+			},
+			Post: PostPropertyConf{
+				Name:      "Name",
+				Authors:   "Authors",
+				CNTitle:   "CN Title",
+				Published: "Published",
+				Link:      "Link",
+				Outline:   "Outline",
+			},
+		},
+	}
+}
+
+// InitConfig loads the defaults, then the YAML file at path if one is given,
+// then the environment variables, and validates the result.
+func InitConfig(path string) error {
+	conf, err := load(path)
+	if err != nil {
+		return err
+	}
+
+	err = conf.Validate()
+	if err != nil {
+		return err
+	}
+
+	conf.apply()
+	return nil
+}
+
+// InitAIConfig is InitConfig for commands that only talk to the LLM and
+// therefore only require a valid ai section.
+func InitAIConfig(path string) error {
+	conf, err := load(path)
+	if err != nil {
+		return err
+	}
+
+	err = errors.Join(conf.validateAI()...)
+	if err != nil {
+		return err
+	}
+
+	conf.apply()
+	return nil
+}
+
+func load(path string) (*Config, error) {
+	conf := defaultConfig()
+	if path != "" {
+		err := loadFile(path, &conf)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-func InitConfig() {
-	Service = ServiceConf{
-		Port:             getEnv("PORT", "8080"),
-		BlogSyncInterval: getEnv("SUBSCRIPTION_SYNC_INTERVAL", "@every 1h"),
+	env := &envLoader{}
+	env.apply(&conf)
+	if len(env.errs) > 0 {
+		return nil, errors.Join(env.errs...)
 	}
+	return &conf, nil
+}
+
+func (c *Config) apply() {
+	Service = c.Service
+	Notion = c.Notion
+	AI = c.AI
+	Email = c.Email
+	Notifiers = c.Notifiers
+	Store = c.Store
+	Pipeline = c.Pipeline
+	Article = c.Article
+	Relevance = c.Relevance
+	Properties = c.Properties
+}
 
-	Notion = NotionConf{
-		NotionApiKey:   getEnv("NOTION_API_KEY", ""),
-		NotionRssDBID:  getEnv("NOTION_RSS_DATABASE_ID", ""),
-		NotionPostDBID: getEnv("NOTION_POST_DATABASE_ID", ""),
+func loadFile(path string, conf *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
 	}
 
-	AI = AIConf{
-		KimiSecretKey: getEnv("MOONSHOT_API_KEY", ""),
-		KimiModel:     getEnv("KIMI_MODEL", "moonshot-v1-32k"),
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err = decoder.Decode(conf)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
 	}
+	return nil
 }
 
-func getEnv(key, fallback string) string {
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(value, name string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+	positive := func(value int, name string) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be greater than 0, got %d", name, value))
+		}
+	}
+
+	if port, err := strconv.Atoi(c.Service.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("service.port %q is not a valid port", c.Service.Port))
+	}
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	if _, err := parser.Parse(c.Service.BlogSyncInterval); err != nil {
+		errs = append(errs, fmt.Errorf("service.sync_interval %q is invalid: %w", c.Service.BlogSyncInterval, err))
+	}
+	positive(c.Service.MaxItemsPerFeed, "service.max_items_per_feed")
+
+	required(c.Notion.NotionApiKey, "notion.api_key (NOTION_API_KEY)")
+	required(c.Notion.NotionRssDBID, "notion.rss_database_id (NOTION_RSS_DATABASE_ID)")
+	required(c.Notion.NotionPostDBID, "notion.post_database_id (NOTION_POST_DATABASE_ID)")
+	required(c.Notion.BaseURL, "notion.base_url")
+	required(c.Notion.Version, "notion.version")
+	positive(c.Notion.RequestsPerSecond, "notion.requests_per_second")
+	positive(c.Notion.TimeoutSeconds, "notion.timeout_seconds")
+
+	errs = append(errs, c.validateAI()...)
+	if c.Relevance.Threshold < 0 || c.Relevance.Threshold > 100 {
+		errs = append(errs, fmt.Errorf("relevance.threshold must be between 0 and 100, got %d", c.Relevance.Threshold))
+	}
+
+	if len(c.Email.Recipients) > 0 {
+		required(c.Email.FROM, "email.from (EMAIL_FROM)")
+		required(c.Email.TemplatePath, "email.template")
+		switch c.Email.Transport {
+		case "resend":
+			required(c.Email.APIKey, "email.resend_api_key (RESEND_API_KEY)")
+		case "smtp":
+			required(c.Email.SMTPHost, "email.smtp_host (SMTP_HOST)")
+			positive(c.Email.SMTPPort, "email.smtp_port")
+		default:
+			errs = append(errs, fmt.Errorf("email.transport %q must be one of resend, smtp", c.Email.Transport))
+		}
+		if c.Email.DigestInterval != "" {
+			if _, err := parser.Parse(c.Email.DigestInterval); err != nil {
+				errs = append(errs, fmt.Errorf("email.digest_interval %q is invalid: %w", c.Email.DigestInterval, err))
+			}
+		}
+	}
+
+	names := map[string]bool{}
+	for i, n := range c.Notifiers {
+		field := fmt.Sprintf("notifiers[%d]", i)
+		required(n.Name, field+".name")
+		if names[n.Name] {
+			errs = append(errs, fmt.Errorf("%s.name %q is duplicated", field, n.Name))
+		}
+		names[n.Name] = true
+
+		switch n.Type {
+		case "slack", "discord", "feishu", "dingtalk", "wecom":
+			required(n.WebhookURL, field+".webhook_url")
+		case "telegram":
+			required(n.BotToken, field+".bot_token")
+			required(n.ChatID, field+".chat_id")
+		default:
+			errs = append(errs, fmt.Errorf("%s.type %q must be one of slack, discord, telegram, feishu, dingtalk, wecom", field, n.Type))
+		}
+	}
+
+	required(c.Store.Path, "store.path")
+	positive(c.Store.MaxAttempts, "store.max_attempts")
+	required(c.Store.LockPath, "store.lock_path")
+
+	positive(c.Pipeline.FetchWorkers, "pipeline.fetch_workers")
+	positive(c.Pipeline.FetchPerHost, "pipeline.fetch_per_host")
+	positive(c.Pipeline.DedupeWorkers, "pipeline.dedupe_workers")
+	positive(c.Pipeline.SummarizeWorkers, "pipeline.summarize_workers")
+	positive(c.Pipeline.WriteWorkers, "pipeline.write_workers")
+
+	positive(c.Article.TimeoutSeconds, "article.timeout_seconds")
+	positive(c.Article.MaxBytes, "article.max_bytes")
+
+	rss := c.Properties.RSS
+	required(rss.Name, "properties.rss.name")
+	required(rss.URL, "properties.rss.url")
+	required(rss.Enabled, "properties.rss.enabled")
+	post := c.Properties.Post
+	required(post.Name, "properties.post.name")
+	required(post.Authors, "properties.post.authors")
+	required(post.CNTitle, "properties.post.cn_title")
+	required(post.Published, "properties.post.published")
+	required(post.Link, "properties.post.link")
+	required(post.Outline, "properties.post.outline")
+	if c.AI.TagsFromNotion {
+		required(post.Tags, "properties.post.tags (required by ai.tags_from_notion)")
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateAI() []error {
+	var errs []error
+	required := func(value, name string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+
+	switch c.AI.Provider {
+	case "moonshot":
+		required(c.AI.KimiSecretKey, "ai.moonshot_api_key (MOONSHOT_API_KEY)")
+		required(c.AI.KimiModel, "ai.kimi_model")
+	case "openai":
+		required(c.AI.OpenAIBaseURL, "ai.openai_base_url")
+		required(c.AI.OpenAIModel, "ai.openai_model")
+	case "ollama":
+		required(c.AI.OllamaBaseURL, "ai.ollama_base_url")
+		required(c.AI.OllamaModel, "ai.ollama_model")
+	default:
+		errs = append(errs, fmt.Errorf("ai.provider %q must be one of moonshot, openai, ollama", c.AI.Provider))
+	}
+
+	required(c.AI.Language, "ai.language")
+	if c.AI.MaxOutputTokens <= 0 {
+		errs = append(errs, fmt.Errorf("ai.max_output_tokens must be greater than 0, got %d", c.AI.MaxOutputTokens))
+	}
+	for i, m := range c.AI.Models {
+		required(m.Name, fmt.Sprintf("ai.models[%d].name", i))
+		if m.ContextTokens <= c.AI.MaxOutputTokens {
+			errs = append(errs, fmt.Errorf("ai.models[%d].context_tokens must be greater than ai.max_output_tokens", i))
+		}
+	}
+
+	return errs
+}
+
+// envLoader overrides config values with environment variables and collects
+// the variables it could not parse.
+type envLoader struct {
+	errs []error
+}
+
+func (e *envLoader) apply(c *Config) {
+	c.Service.Port = e.getEnv("PORT", c.Service.Port)
+	c.Service.BlogSyncInterval = e.getEnv("SUBSCRIPTION_SYNC_INTERVAL", c.Service.BlogSyncInterval)
+	c.Service.MaxItemsPerFeed = e.getEnvInt("SUBSCRIPTION_MAX_ITEMS", c.Service.MaxItemsPerFeed)
+	c.Service.AdminToken = e.getEnv("ADMIN_TOKEN", c.Service.AdminToken)
+
+	c.Notion.NotionApiKey = e.getEnv("NOTION_API_KEY", c.Notion.NotionApiKey)
+	c.Notion.NotionRssDBID = e.getEnv("NOTION_RSS_DATABASE_ID", c.Notion.NotionRssDBID)
+	c.Notion.NotionPostDBID = e.getEnv("NOTION_POST_DATABASE_ID", c.Notion.NotionPostDBID)
+	c.Notion.RequestsPerSecond = e.getEnvInt("NOTION_REQUESTS_PER_SECOND", c.Notion.RequestsPerSecond)
+	c.Notion.BaseURL = e.getEnv("NOTION_BASE_URL", c.Notion.BaseURL)
+	c.Notion.Version = e.getEnv("NOTION_VERSION", c.Notion.Version)
+	c.Notion.TimeoutSeconds = e.getEnvInt("NOTION_TIMEOUT_SECONDS", c.Notion.TimeoutSeconds)
+	c.Notion.AutoFixSchema = e.getEnvBool("NOTION_SCHEMA_AUTOFIX", c.Notion.AutoFixSchema)
+	c.Notion.PromptPageID = e.getEnv("NOTION_PROMPT_PAGE_ID", c.Notion.PromptPageID)
+
+	c.AI.Provider = e.getEnv("AI_PROVIDER", c.AI.Provider)
+	c.AI.KimiSecretKey = e.getEnv("MOONSHOT_API_KEY", c.AI.KimiSecretKey)
+	c.AI.KimiModel = e.getEnv("KIMI_MODEL", c.AI.KimiModel)
+	c.AI.OpenAIBaseURL = e.getEnv("OPENAI_BASE_URL", c.AI.OpenAIBaseURL)
+	c.AI.OpenAIAPIKey = e.getEnv("OPENAI_API_KEY", c.AI.OpenAIAPIKey)
+	c.AI.OpenAIModel = e.getEnv("OPENAI_MODEL", c.AI.OpenAIModel)
+	c.AI.OllamaBaseURL = e.getEnv("OLLAMA_BASE_URL", c.AI.OllamaBaseURL)
+	c.AI.OllamaModel = e.getEnv("OLLAMA_MODEL", c.AI.OllamaModel)
+	c.AI.Models = e.getEnvModels("AI_MODELS", c.AI.Models)
+	c.AI.MaxOutputTokens = e.getEnvInt("AI_MAX_OUTPUT_TOKENS", c.AI.MaxOutputTokens)
+	c.AI.Language = e.getEnv("AI_LANGUAGE", c.AI.Language)
+	c.AI.PromptDir = e.getEnv("AI_PROMPT_DIR", c.AI.PromptDir)
+	c.AI.Style = e.getEnv("AI_STYLE", c.AI.Style)
+	c.AI.Tags = e.getEnvList("AI_TAGS", c.AI.Tags)
+	c.AI.TagsFromNotion = e.getEnvBool("AI_TAGS_FROM_NOTION", c.AI.TagsFromNotion)
+
+	c.Email.Transport = e.getEnv("EMAIL_TRANSPORT", c.Email.Transport)
+	c.Email.APIKey = e.getEnv("RESEND_API_KEY", c.Email.APIKey)
+	c.Email.FROM = e.getEnv("EMAIL_FROM", c.Email.FROM)
+	c.Email.Recipients = e.getEnvList("EMAIL_TO", c.Email.Recipients)
+	c.Email.Subject = e.getEnv("EMAIL_SUBJECT", c.Email.Subject)
+	c.Email.TemplatePath = e.getEnv("EMAIL_TEMPLATE", c.Email.TemplatePath)
+	c.Email.DigestInterval = e.getEnv("EMAIL_DIGEST_INTERVAL", c.Email.DigestInterval)
+	c.Email.SMTPHost = e.getEnv("SMTP_HOST", c.Email.SMTPHost)
+	c.Email.SMTPPort = e.getEnvInt("SMTP_PORT", c.Email.SMTPPort)
+	c.Email.SMTPUsername = e.getEnv("SMTP_USERNAME", c.Email.SMTPUsername)
+	c.Email.SMTPPassword = e.getEnv("SMTP_PASSWORD", c.Email.SMTPPassword)
+
+	c.Store.Path = e.getEnv("STATE_DB_PATH", c.Store.Path)
+	c.Store.MaxAttempts = e.getEnvInt("STATE_MAX_ATTEMPTS", c.Store.MaxAttempts)
+	c.Store.LockPath = e.getEnv("SYNC_LOCK_PATH", c.Store.LockPath)
+
+	c.Pipeline.FetchWorkers = e.getEnvInt("PIPELINE_FETCH_WORKERS", c.Pipeline.FetchWorkers)
+	c.Pipeline.FetchPerHost = e.getEnvInt("PIPELINE_FETCH_PER_HOST", c.Pipeline.FetchPerHost)
+	c.Pipeline.DedupeWorkers = e.getEnvInt("PIPELINE_DEDUPE_WORKERS", c.Pipeline.DedupeWorkers)
+	c.Pipeline.SummarizeWorkers = e.getEnvInt("PIPELINE_SUMMARIZE_WORKERS", c.Pipeline.SummarizeWorkers)
+	c.Pipeline.WriteWorkers = e.getEnvInt("PIPELINE_WRITE_WORKERS", c.Pipeline.WriteWorkers)
+
+	c.Article.Fetch = e.getEnvBool("ARTICLE_FETCH", c.Article.Fetch)
+	c.Article.TimeoutSeconds = e.getEnvInt("ARTICLE_TIMEOUT_SECONDS", c.Article.TimeoutSeconds)
+	c.Article.MaxBytes = e.getEnvInt("ARTICLE_MAX_BYTES", c.Article.MaxBytes)
+	c.Article.MinLength = e.getEnvInt("ARTICLE_MIN_LENGTH", c.Article.MinLength)
+
+	c.Relevance.Interests = e.getEnvList("RELEVANCE_INTERESTS", c.Relevance.Interests)
+	c.Relevance.Ignore = e.getEnvList("RELEVANCE_IGNORE", c.Relevance.Ignore)
+	c.Relevance.ProfilePageID = e.getEnv("RELEVANCE_PROFILE_PAGE_ID", c.Relevance.ProfilePageID)
+	c.Relevance.Threshold = e.getEnvInt("RELEVANCE_THRESHOLD", c.Relevance.Threshold)
+
+	rssMapping := e.getEnvMapping("NOTION_RSS_PROPERTY_MAP")
+	rss := &c.Properties.RSS
+	rss.Name = rssMapping.name("name", rss.Name)
+	rss.URL = rssMapping.name("url", rss.URL)
+	rss.Enabled = rssMapping.name("enabled", rss.Enabled)
+	rss.MaxItems = rssMapping.name("max_items", rss.MaxItems)
+	rss.LastPublished = rssMapping.name("last_published", rss.LastPublished)
+	rss.LastGUID = rssMapping.name("last_guid", rss.LastGUID)
+	rss.Notify = rssMapping.name("notify", rss.Notify)
+	rss.Prompt = rssMapping.name("prompt", rss.Prompt)
+	rss.Language = rssMapping.name("language", rss.Language)
+	rss.Style = rssMapping.name("style", rss.Style)
+	e.checkMapping(rssMapping)
+
+	postMapping := e.getEnvMapping("NOTION_POST_PROPERTY_MAP")
+	post := &c.Properties.Post
+	post.Name = postMapping.name("name", post.Name)
+	post.Authors = postMapping.name("authors", post.Authors)
+	post.CNTitle = postMapping.name("cn_title", post.CNTitle)
+	post.Published = postMapping.name("published", post.Published)
+	post.Link = postMapping.name("link", post.Link)
+	post.Outline = postMapping.name("outline", post.Outline)
+	post.Tags = postMapping.name("tags", post.Tags)
+	post.Score = postMapping.name("score", post.Score)
+	post.Style = postMapping.name("style", post.Style)
+	e.checkMapping(postMapping)
+}
+
+// getEnv reads key, or the file named by key_FILE so secrets can be mounted
+// as files, and falls back to the value from the config file.
+func (e *envLoader) getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
 	}
+	if path, exists := os.LookupEnv(key + "_FILE"); exists {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("read %s_FILE: %w", key, err))
+			return fallback
+		}
+		return strings.TrimSpace(string(content))
+	}
 	return fallback
 }
+
+func (e *envLoader) getEnvInt(key string, fallback int) int {
+	value := e.getEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s=%q is not an integer", key, value))
+		return fallback
+	}
+	return i
+}
+
+func (e *envLoader) getEnvBool(key string, fallback bool) bool {
+	value := e.getEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s=%q is not a boolean", key, value))
+		return fallback
+	}
+	return b
+}
+
+// getEnvList reads a comma separated list.
+func (e *envLoader) getEnvList(key string, fallback []string) []string {
+	value := e.getEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvModels parses "moonshot-v1-8k=8192,moonshot-v1-32k=32768" into models,
+// keeping their order.
+func (e *envLoader) getEnvModels(key string, fallback []ModelConf) []ModelConf {
+	value := e.getEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+
+	pairs, err := parsePairs(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+	}
+	var models []ModelConf
+	for _, p := range pairs {
+		contextTokens, err := strconv.Atoi(p.value)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: context size %q of %s is not an integer", key, p.value, p.key))
+			continue
+		}
+		models = append(models, ModelConf{Name: p.key, ContextTokens: contextTokens})
+	}
+	return models
+}
+
+// propertyMapping is a "field=Property Name,other=名称" variable. It records
+// the fields looked up, the others are unknown.
+type propertyMapping struct {
+	key   string
+	names map[string]string
+	order []string
+	used  map[string]bool
+}
+
+// getEnvMapping parses the property mapping in key.
+func (e *envLoader) getEnvMapping(key string) *propertyMapping {
+	m := &propertyMapping{key: key, names: map[string]string{}, used: map[string]bool{}}
+	pairs, err := parsePairs(e.getEnv(key, ""))
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %w", key, err))
+	}
+	for _, p := range pairs {
+		m.names[p.key] = p.value
+		m.order = append(m.order, p.key)
+	}
+	return m
+}
+
+// name is the property mapped to field, or fallback.
+func (m *propertyMapping) name(field, fallback string) string {
+	m.used[field] = true
+	if name, exists := m.names[field]; exists {
+		return name
+	}
+	return fallback
+}
+
+// checkMapping reports the fields of m that were never looked up.
+func (e *envLoader) checkMapping(m *propertyMapping) {
+	for _, field := range m.order {
+		if !m.used[field] {
+			e.errs = append(e.errs, fmt.Errorf("%s: unknown field %q", m.key, field))
+		}
+	}
+}
+
+type pair struct {
+	key, value string
+}
+
+// parsePairs parses "key=value,other=值" in order, reporting every part that
+// is not a key=value pair.
+func parsePairs(value string) ([]pair, error) {
+	var pairs []pair
+	var errs []error
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			errs = append(errs, fmt.Errorf("%q is not a key=value pair", strings.TrimSpace(part)))
+			continue
+		}
+		pairs = append(pairs, pair{key: key, value: strings.TrimSpace(val)})
+	}
+	return pairs, errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetEnvModels(t *testing.T) {
+	t.Setenv("AI_MODELS", "moonshot-v1-128k=131072, moonshot-v1-8k=8192,moonshot-v1-32k=32768")
+
+	e := &envLoader{}
+	got := e.getEnvModels("AI_MODELS", nil)
+	want := []ModelConf{
+		{Name: "moonshot-v1-128k", ContextTokens: 131072},
+		{Name: "moonshot-v1-8k", ContextTokens: 8192},
+		{Name: "moonshot-v1-32k", ContextTokens: 32768},
+	}
+	if len(e.errs) > 0 {
+		t.Fatalf("getEnvModels() errors = %v", e.errs)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getEnvModels() = %v, want %v in order", got, want)
+	}
+}
+
+func TestEnvMappingErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		wantErrs int
+	}{
+		{name: "valid", value: "name=名称, tags=", wantErrs: 0},
+		{name: "empty parts", value: "name=名称,,", wantErrs: 0},
+		{name: "missing equals", value: "name=名称,tags", wantErrs: 1},
+		{name: "missing field", value: "=名称", wantErrs: 1},
+		{name: "unknown field", value: "name=名称,titel=标题", wantErrs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOTION_POST_PROPERTY_MAP", tt.value)
+
+			e := &envLoader{}
+			m := e.getEnvMapping("NOTION_POST_PROPERTY_MAP")
+			m.name("name", "Name")
+			m.name("tags", "Tags")
+			e.checkMapping(m)
+			if len(e.errs) != tt.wantErrs {
+				t.Errorf("got errors %v, want %d", e.errs, tt.wantErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package kimi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"notion-summary/config"
+	"notion-summary/llm"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// messageOverhead is the tokens a chat message costs besides its content.
+const messageOverhead = 16
+
+// maxReduceDepth bounds how many times partial summaries are summarized again.
+const maxReduceDepth = 2
+
+var ErrArticleTooLong = errors.New("article is too long even for chunked summarization")
+
+// moonshotModels are the context sizes of the Moonshot models, used to size
+// KIMI_MODEL when no models are configured.
+var moonshotModels = []config.ModelConf{
+	{Name: "moonshot-v1-8k", ContextTokens: 8192},
+	{Name: "moonshot-v1-32k", ContextTokens: 32768},
+	{Name: "moonshot-v1-128k", ContextTokens: 131072},
+}
+
+var chunkSummaryPrompt = template.Must(template.New("chunk").Parse(`你是一个擅长阅读长文章的小助手。用户会给出一篇长文章中的一部分，请用{{.}}按原文顺序列出这一部分的要点，尽量保留关键的事实、数据、观点与结论。
+不要编造原文中没有的内容，不要输出标题、开场白或评价，只输出要点列表。`))
+
+// chunkSystem renders the system message that reduces a chunk to its key
+// points in language.
+func chunkSystem(language string) (llm.Message, error) {
+	var b strings.Builder
+	err := chunkSummaryPrompt.Execute(&b, language)
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("render chunk prompt: %w", err)
+	}
+	return llm.Message{Role: llm.ROLE_SYSTEM, Content: b.String()}, nil
+}
+
+// defaultContextTokens is the context size of a Moonshot model missing from
+// moonshotModels.
+const defaultContextTokens = 8192
+
+// modelTiers returns the configured models from the smallest context to the
+// largest, or else the provider's configured model as the only tier.
+func modelTiers(conf config.AIConf) []config.ModelConf {
+	models := slices.Clone(conf.Models)
+	if len(models) == 0 {
+		switch conf.Provider {
+		case "", llm.PROVIDER_MOONSHOT:
+			models = []config.ModelConf{{Name: conf.KimiModel, ContextTokens: moonshotContext(conf.KimiModel)}}
+		case llm.PROVIDER_OPENAI:
+			models = []config.ModelConf{{Name: conf.OpenAIModel, ContextTokens: 128000}}
+		case llm.PROVIDER_OLLAMA:
+			models = []config.ModelConf{{Name: conf.OllamaModel, ContextTokens: 8192}}
+		}
+	}
+
+	sort.SliceStable(models, func(i, j int) bool {
+		return models[i].ContextTokens < models[j].ContextTokens
+	})
+	return models
+}
+
+// moonshotContext looks up the context size of the Moonshot model name.
+func moonshotContext(name string) int {
+	for _, model := range moonshotModels {
+		if model.Name == name {
+			return model.ContextTokens
+		}
+	}
+	return defaultContextTokens
+}
+
+// EstimateTokens approximates the tokens of s without the model's tokenizer,
+// erring on the high side: a CJK character counts as one token, other text as
+// one token every four characters.
+func EstimateTokens(s string) int {
+	cjk, other := 0, 0
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// budget is how many tokens the user message may take with model and system,
+// leaving MaxOutputTokens for the answer.
+func (s *ChatSummarizer) budget(model config.ModelConf, system llm.Message) int {
+	return model.ContextTokens - s.MaxOutputTokens - EstimateTokens(system.Content) - 2*messageOverhead
+}
+
+// pickModel returns the smallest model that fits prompt.
+func (s *ChatSummarizer) pickModel(system llm.Message, prompt string) (config.ModelConf, bool) {
+	tokens := EstimateTokens(prompt)
+	for _, model := range s.Models {
+		if tokens <= s.budget(model, system) {
+			return model, true
+		}
+	}
+	return config.ModelConf{}, false
+}
+
+// summarizeLong summarizes the article with the smallest model that fits it.
+// When none does, the text is cut into chunks that fit the largest model,
+// every chunk is reduced to its key points and the key points are summarized
+// in place of the text.
+func (s *ChatSummarizer) summarizeLong(ctx context.Context, article Article, depth int) (*Summary, error) {
+	system, prompt, err := s.messages(article)
+	if err != nil {
+		return nil, err
+	}
+	if model, ok := s.pickModel(system, prompt); ok {
+		return s.summarize(ctx, model.Name, system, prompt, summarySchema(article.Vocabulary))
+	}
+	if depth >= maxReduceDepth {
+		return nil, ErrArticleTooLong
+	}
+
+	chunkSys, err := chunkSystem(s.language(article))
+	if err != nil {
+		return nil, err
+	}
+	largest := s.Models[len(s.Models)-1]
+	header := chunkPrompt(article, 1, 1, "")
+	chunks := splitChunks(article.Text, s.budget(largest, chunkSys)-EstimateTokens(header)-32)
+	log.Printf("%s is too long for %s, summarize it in %d chunks\n", article.Link, largest.Name, len(chunks))
+
+	var points strings.Builder
+	points.WriteString("（原文过长，以下是按原文顺序排列的各部分要点）\n")
+	for i, chunk := range chunks {
+		prompt := chunkPrompt(article, i+1, len(chunks), chunk)
+		model, _ := s.pickModel(chunkSys, prompt)
+		partial, err := s.chat(ctx, model.Name, chunkSys, prompt, nil)
+		if err != nil {
+			return nil, fmt.Errorf("summarize chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		fmt.Fprintf(&points, "\n#### 第%d部分\n%s\n", i+1, strings.TrimSpace(partial))
+	}
+
+	article.Text = points.String()
+	return s.summarizeLong(ctx, article, depth+1)
+}
+
+func chunkPrompt(article Article, i, n int, chunk string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "链接：%s\n", article.Link)
+	if article.Title != "" {
+		fmt.Fprintf(&b, "标题：%s\n", article.Title)
+	}
+	fmt.Fprintf(&b, "这是文章的第%d/%d部分：\n%s", i, n, chunk)
+	return b.String()
+}
+
+// splitChunks cuts text into chunks of at most budget tokens, at paragraph
+// boundaries where possible.
+func splitChunks(text string, budget int) []string {
+	budget = max(budget, 1)
+
+	var chunks []string
+	var chunk strings.Builder
+	tokens := 0
+	flush := func() {
+		if chunk.Len() > 0 {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+			tokens = 0
+		}
+	}
+
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		for _, part := range splitParagraph(paragraph, budget) {
+			n := EstimateTokens(part) + 1
+			if tokens+n > budget {
+				flush()
+			}
+			if chunk.Len() > 0 {
+				chunk.WriteString("\n\n")
+			}
+			chunk.WriteString(part)
+			tokens += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitParagraph cuts a paragraph longer than budget tokens every budget
+// characters, which never exceeds budget tokens.
+func splitParagraph(paragraph string, budget int) []string {
+	if EstimateTokens(paragraph) <= budget {
+		return []string{paragraph}
+	}
+
+	var parts []string
+	runes := []rune(paragraph)
+	for len(runes) > 0 {
+		n := min(len(runes), budget)
+		parts = append(parts, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return parts
+}
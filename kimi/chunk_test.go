@@ -0,0 +1,62 @@
+package kimi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "latin", text: "abcdefgh", want: 2},
+		{name: "latin rounded up", text: "abcde", want: 2},
+		{name: "chinese", text: "你好世界", want: 4},
+		{name: "japanese", text: "ひらがなカタカナ", want: 8},
+		{name: "korean", text: "안녕하세요", want: 5},
+		{name: "mixed", text: "Go语言ok", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	cjkParagraph := strings.Repeat("长", 50)
+
+	tests := []struct {
+		name   string
+		text   string
+		budget int
+		want   int
+	}{
+		{name: "fits", text: "第一段。\n\n第二段。", budget: 100, want: 1},
+		{name: "one paragraph per chunk", text: cjkParagraph + "\n\n" + cjkParagraph, budget: 60, want: 2},
+		{name: "long cjk paragraph cut", text: strings.Repeat("字", 250), budget: 100, want: 3},
+		{name: "zero budget", text: "一二三", budget: 0, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitChunks(tt.text, tt.budget)
+			if len(chunks) != tt.want {
+				t.Fatalf("splitChunks() returned %d chunks, want %d", len(chunks), tt.want)
+			}
+
+			for _, chunk := range chunks {
+				if tokens := EstimateTokens(chunk); tokens > max(tt.budget, 1) {
+					t.Errorf("chunk of %d tokens exceeds the budget of %d", tokens, tt.budget)
+				}
+			}
+			if got := strings.ReplaceAll(strings.Join(chunks, ""), "\n\n", ""); got != strings.ReplaceAll(tt.text, "\n\n", "") {
+				t.Errorf("chunks do not add up to the text")
+			}
+		})
+	}
+}
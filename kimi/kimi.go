@@ -1,129 +1,272 @@
 package kimi
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
 	"notion-summary/config"
+	"notion-summary/llm"
+	"notion-summary/metrics"
+	"strings"
+	"time"
 )
 
-const ROLE_SYSTEM = "system"
-const ROLE_USER = "user"
-const ROLE_ASSISTANT = "assistant"
-
 var ErrEmptyPrompt = errors.New("prompt is empty")
 
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// Article is what a summary is made of. Without Text the model is only given
+// the link and reads the article itself.
+type Article struct {
+	Link   string
+	Title  string
+	Author string
+	// Feed is the name of the subscription the article comes from.
+	Feed string
+	// Text is the content of the article in Markdown.
+	Text string
+	// Template names the prompt template, empty means the default one.
+	Template string
+	// Language is the language of the summary, empty means the summarizer's.
+	Language string
+	// Style is the style of the summary, empty means the summarizer's.
+	Style Style
+	// Vocabulary is the tags the summary picks from, empty means any tags.
+	Vocabulary []string
+	// Profile is the interest profile the article is scored against, empty
+	// means the article is not scored.
+	Profile string
+	// Relevance is the score of an article scored beforehand, it is not
+	// scored again.
+	Relevance *Relevance
 }
 
-type APIRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float32   `json:"temperature"`
+// Prompt is the user message asking for the summary of the article.
+func (a Article) Prompt() string {
+	if strings.TrimSpace(a.Text) == "" {
+		return a.Link
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "链接：%s\n", a.Link)
+	if a.Title != "" {
+		fmt.Fprintf(&b, "标题：%s\n", a.Title)
+	}
+	fmt.Fprintf(&b, "正文：\n%s", a.Text)
+	return b.String()
 }
 
-type APIResponse struct {
-	ID      string   `json:"id"`
-	Object  string   `json:"object"`
-	Created int64    `json:"created"`
-	Model   string   `json:"model"`
-	Choices []Choice `json:"choices"`
-	Usage   Usage    `json:"usage"`
+func (a Article) promptData() PromptData {
+	return PromptData{
+		Title:    a.Title,
+		Author:   a.Author,
+		Feed:     a.Feed,
+		Link:     a.Link,
+		Content:  a.Text,
+		Language: a.Language,
+	}
 }
 
-type Choice struct {
-	Index        int     `json:"index"`
-	Message      Message `json:"message"`
-	FinishReason string  `json:"finish_reason"`
+// messages renders the system prompt and the user message of the article.
+// The text is left out of the user message when the template already
+// includes it.
+func (s *ChatSummarizer) messages(article Article) (system llm.Message, prompt string, err error) {
+	system, err = s.systemPrompt(article)
+	if err != nil {
+		return llm.Message{}, "", err
+	}
+
+	text := strings.TrimSpace(article.Text)
+	if text != "" && strings.Contains(system.Content, text) {
+		article.Text = ""
+	}
+	return system, article.Prompt(), nil
 }
 
-type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+// Summarizer turns an article into a structured summary.
+type Summarizer interface {
+	Summarize(ctx context.Context, article Article) (*Summary, error)
 }
 
-var blogSummaryPrompt = `角色
-你是一个擅长给文章做概要和总结的小助手，你将针对用户给出的链接，经过对链接的访问读取和内容的分析后，对文章的内容作出专业的概要和总结。
+// Scorer rates an article against its interest profile, so the score can be
+// taken once and reused when the summary is retried.
+type Scorer interface {
+	Score(ctx context.Context, article Article) (*Relevance, error)
+}
 
-结果输出要求
-一、输出的结果必须是中文
-不论原文是什么语言，最终输出的结果都必须是中文。
+// ChatSummarizer summarizes articles with the blog summary prompt on top of any ChatProvider.
+type ChatSummarizer struct {
+	Provider llm.ChatProvider
+	// Models are tried from the smallest context up, an article too long for
+	// all of them is summarized in chunks. Empty sends every article as is to
+	// the provider's model.
+	Models          []config.ModelConf
+	MaxOutputTokens int
+	// Prompts are the templates articles pick from, nil means only the built-in one.
+	Prompts *Prompts
+	// Language is the language of summaries whose article does not pick one.
+	Language string
+	// Style is the style of summaries whose article does not pick one.
+	Style Style
+	// Threshold is the relevance score below which scored articles are not
+	// summarized, 0 summarizes every article.
+	Threshold int
+}
 
-二、需要输出的内容
-1. 先从文章的内容里提取出标题
-1. 再根据文章的内容给出简单的概要，这部份可以不用太详细。
-2. 最后再根据文章的内容给出详细的总结，总结的内容要尽可能的详细，要能够覆盖文章大部份的要点。
+func NewChatSummarizer(provider llm.ChatProvider) *ChatSummarizer {
+	return &ChatSummarizer{Provider: provider}
+}
 
-三、严格按照给定的格式进行输出
-根据之前描述的要求得到标题、概要和总结后，输出的结果必须是markdown格式，并严格遵循以下模板进行输出（注意：模板中的{标题}、{概要}、{总结}要替换成你获取到的标题、概要和总结）：
+func (s *ChatSummarizer) Summarize(ctx context.Context, article Article) (*Summary, error) {
+	if article.Link == "" && strings.TrimSpace(article.Text) == "" {
+		return nil, ErrEmptyPrompt
+	}
 
-## 标题
-{标题}
-### 概要
-{概要}
-### 总结
-{总结}
-`
+	relevance := article.Relevance
+	var err error
+	if relevance == nil {
+		relevance, err = s.Score(ctx, article)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if relevance != nil && relevance.Score < s.Threshold {
+		log.Printf("%s scored %d, below the threshold %d\n", article.Link, relevance.Score, s.Threshold)
+		return belowThreshold(article, relevance), nil
+	}
 
-var BaseBlogSummaryPrompt = Message{Role: ROLE_SYSTEM, Content: blogSummaryPrompt}
+	var summary *Summary
+	if len(s.Models) == 0 {
+		system, prompt, msgErr := s.messages(article)
+		if msgErr != nil {
+			return nil, msgErr
+		}
+		summary, err = s.summarize(ctx, "", system, prompt, summarySchema(article.Vocabulary))
+	} else {
+		summary, err = s.summarizeLong(ctx, article, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-func SendChatRequest(prompt string) (result string, err error) {
-	if prompt == "" {
-		return "", ErrEmptyPrompt
+	summary.Style = s.style(article)
+	summary.Tags = matchTags(summary.Tags, article.Vocabulary)
+	summary.Relevance = relevance
+	return summary, nil
+}
+
+// maxReasks is how many times the model is asked again after an invalid answer.
+const maxReasks = 2
+
+// summarize asks model for the JSON summary of prompt.
+func (s *ChatSummarizer) summarize(ctx context.Context, model string, system llm.Message, prompt string, schema json.RawMessage) (*Summary, error) {
+	var summary *Summary
+	err := s.ask(ctx, model, system, prompt, schema, func(content string) (err error) {
+		summary, err = ParseSummary(content)
+		return err
+	})
+	return summary, err
+}
+
+// ask sends prompt to model and hands the answer to parse. An answer parse
+// rejects is asked for again, telling the model what was wrong with it.
+func (s *ChatSummarizer) ask(ctx context.Context, model string, system llm.Message, prompt string, schema json.RawMessage, parse func(content string) error) error {
+	request := prompt
+	for attempt := 0; ; attempt++ {
+		content, err := s.chat(ctx, model, system, request, schema)
+		if err != nil {
+			return err
+		}
+
+		err = parse(content)
+		if err == nil {
+			return nil
+		}
+		if attempt == maxReasks {
+			return err
+		}
+
+		log.Printf("ask again, %v\n", err)
+		request = prompt + fmt.Sprintf("\n\n注意：你上一次的输出无效（%v），请严格按照要求只输出JSON对象。", err)
 	}
+}
 
-	url := "https://api.moonshot.cn/v1/chat/completions"
-	requestBody, _ := json.Marshal(APIRequest{
-		Model: config.AI.KimiModel,
-		Messages: []Message{
-			BaseBlogSummaryPrompt,
-			{Role: ROLE_USER, Content: prompt},
+// chat sends a single request, an empty model means the provider's model.
+func (s *ChatSummarizer) chat(ctx context.Context, model string, system llm.Message, prompt string, schema json.RawMessage) (string, error) {
+	start := time.Now()
+	resp, err := s.Provider.Chat(ctx, llm.ChatRequest{
+		Model: model,
+		Messages: []llm.Message{
+			system,
+			{Role: llm.ROLE_USER, Content: prompt},
 		},
 		Temperature: 0.3,
+		JSONSchema:  schema,
 	})
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return
+		metrics.ObserveChat(s.Provider.Name(), start, 0, 0, err)
+		return "", err
 	}
+	metrics.ObserveChat(s.Provider.Name(), start, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, nil)
 
-	req.Header.Add("Authorization", "Bearer "+config.AI.KimiSecretKey)
-	req.Header.Add("Content-Type", "application/json")
+	return resp.Content, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error making request:", err)
-		return
-	}
-	defer resp.Body.Close()
+var defaultSummarizer Summarizer
+var defaultPrompts *Prompts
 
-	body, err := io.ReadAll(resp.Body)
+// InitSummarizer builds the default summarizer from the provider selected in config.
+func InitSummarizer() error {
+	provider, err := llm.NewChatProvider(config.AI)
 	if err != nil {
-		fmt.Println("Error reading response:", err)
-		return
+		return err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		err = fmt.Errorf("statusCode %d, request error: %v", resp.StatusCode, string(body))
-		return
+	prompts := NewPrompts()
+	if config.AI.PromptDir != "" {
+		err = prompts.LoadDir(config.AI.PromptDir)
+		if err != nil {
+			return err
+		}
 	}
 
-	respData := APIResponse{}
-	err = json.Unmarshal(body, &respData)
+	summarizer := NewChatSummarizer(provider)
+	summarizer.Models = modelTiers(config.AI)
+	summarizer.MaxOutputTokens = config.AI.MaxOutputTokens
+	summarizer.Prompts = prompts
+	summarizer.Language = config.AI.Language
+	summarizer.Threshold = config.Relevance.Threshold
+	summarizer.Style, err = ParseStyle(config.AI.Style)
 	if err != nil {
-		return
+		return err
 	}
+	defaultSummarizer = summarizer
+	defaultPrompts = prompts
+	return nil
+}
+
+// DefaultSummarizer returns the summarizer built by InitSummarizer.
+func DefaultSummarizer() Summarizer {
+	return defaultSummarizer
+}
 
-	if len(respData.Choices) == 0 {
-		return
+// DefaultPrompts returns the prompt templates of the default summarizer.
+func DefaultPrompts() *Prompts {
+	return defaultPrompts
+}
+
+// SendChatRequest summarizes the article at link, leaving the reading to the
+// model, and returns the summary as Markdown.
+func SendChatRequest(link string) (result string, err error) {
+	if defaultSummarizer == nil {
+		if err = InitSummarizer(); err != nil {
+			return
+		}
 	}
 
-	result = respData.Choices[0].Message.Content
-	return
+	summary, err := defaultSummarizer.Summarize(context.Background(), Article{Link: link})
+	if err != nil {
+		return "", err
+	}
+	return summary.Markdown(), nil
 }
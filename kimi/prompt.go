@@ -0,0 +1,257 @@
+package kimi
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"notion-summary/llm"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultPrompt is the name of the built-in prompt template.
+const DefaultPrompt = "default"
+
+// DefaultLanguage is the language summaries are written in unless configured otherwise.
+const DefaultLanguage = "中文"
+
+var ErrUnknownPrompt = errors.New("unknown prompt template")
+
+// promptExtensions are the files of a prompt directory loaded as templates.
+var promptExtensions = []string{".tmpl", ".txt", ".md"}
+
+var blogSummaryPrompt = `角色
+你是一个擅长给文章做概要和总结的小助手，用户会给出文章的链接、标题和正文，你需要基于给出的正文进行分析，对文章的内容作出专业的概要和总结，不要编造正文中没有的内容。如果用户只给出了链接，则访问链接读取文章内容后再进行总结。
+
+结果输出要求
+一、输出的结果必须是{{.Language}}
+不论原文是什么语言，最终输出的结果都必须是{{.Language}}（language字段除外）。
+
+二、需要输出的内容
+1. 先从文章的内容里提取出标题
+2. 再根据文章的内容给出简单的概要，这部份可以不用太详细。
+3. 然后列出文章的关键要点，并给出几个主题标签。
+4. 最后再根据文章的内容给出总结，总结的详略以输出格式中的要求为准。
+`
+
+// outputFormat is appended to every prompt template, so a custom template
+// cannot break the structured answer. It is executed with a formatData.
+var outputFormat = template.Must(template.New("format").Parse(`
+输出格式
+{{with .Spec.Note}}{{.}}
+{{end}}只输出一个JSON对象，不要输出代码块标记或任何其他文字。除language字段外，所有字段都使用{{.Language}}。JSON对象包含以下字段：
+- title：文章标题的{{.Language}}翻译，原文已是{{.Language}}则保留原标题
+- outline：{{.Spec.Outline}}
+- summary：{{.Spec.Summary}}
+- key_points：{{.Spec.KeyPoints}}
+{{if .Vocabulary}}- tags：字符串数组，从以下标签中选出一到五个与文章最相关的，只能使用列表中的标签，原样输出：{{.Vocabulary}}
+{{else}}- tags：字符串数组，文章的主题标签，一到五个
+{{end}}- language：原文的语言，使用ISO 639-1代码，例如en、zh、ja
+
+示例：
+{"title": "标题", "outline": "概要", "summary": "总结", "key_points": ["要点一", "要点二"], "tags": ["标签"], "language": "en"}
+`))
+
+// PromptData is what a prompt template is executed with.
+type PromptData struct {
+	Title    string
+	Author   string
+	Feed     string
+	Link     string
+	Content  string
+	Language string
+	// Style is the name of the summary style, e.g. tldr or technical.
+	Style string
+}
+
+type formatData struct {
+	PromptData
+	Spec styleSpec
+	// Vocabulary lists the tags to pick from, empty means any tags.
+	Vocabulary string
+}
+
+// Prompts holds the prompt templates by name. The built-in template and the
+// ones loaded from files can be overridden by a set replaced at runtime,
+// e.g. the templates read from a Notion page.
+type Prompts struct {
+	mu        sync.RWMutex
+	base      map[string]*template.Template
+	overrides map[string]*template.Template
+}
+
+// NewPrompts returns the prompts with only the built-in template.
+func NewPrompts() *Prompts {
+	return &Prompts{
+		base: map[string]*template.Template{
+			DefaultPrompt: template.Must(template.New(DefaultPrompt).Parse(blogSummaryPrompt)),
+		},
+	}
+}
+
+// LoadDir adds every template file of dir, named after the file without its
+// extension. A template named default replaces the built-in one.
+func (p *Prompts) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read prompt dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !slices.Contains(promptExtensions, ext) {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("read prompt: %w", err)
+		}
+		name := strings.TrimSuffix(entry.Name(), ext)
+		tmpl, err := parsePrompt(name, string(content))
+		if err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		p.base[name] = tmpl
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// Override replaces the templates layered over the built-in and file ones.
+// Nothing changes when one of texts does not parse.
+func (p *Prompts) Override(texts map[string]string) error {
+	overrides := make(map[string]*template.Template, len(texts))
+	for name, text := range texts {
+		tmpl, err := parsePrompt(name, text)
+		if err != nil {
+			return err
+		}
+		overrides[name] = tmpl
+	}
+
+	p.mu.Lock()
+	p.overrides = overrides
+	p.mu.Unlock()
+	return nil
+}
+
+// Names lists the templates a subscription can pick.
+func (p *Prompts) Names() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var names []string
+	for name := range p.base {
+		names = append(names, name)
+	}
+	for name := range p.overrides {
+		if _, ok := p.base[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (p *Prompts) lookup(name string) (*template.Template, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if tmpl, ok := p.overrides[name]; ok {
+		return tmpl, true
+	}
+	tmpl, ok := p.base[name]
+	return tmpl, ok
+}
+
+// System renders the system message of template name with data, followed by
+// the output format asking for tags from vocabulary. An empty name is the
+// default template.
+func (p *Prompts) System(name string, data PromptData, vocabulary []string) (llm.Message, error) {
+	if name == "" {
+		name = DefaultPrompt
+	}
+	if data.Language == "" {
+		data.Language = DefaultLanguage
+	}
+	if data.Style == "" {
+		data.Style = string(DefaultStyle)
+	}
+	tmpl, ok := p.lookup(name)
+	if !ok {
+		return llm.Message{}, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+
+	var b strings.Builder
+	err := tmpl.Execute(&b, data)
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("render prompt %s: %w", name, err)
+	}
+	err = outputFormat.Execute(&b, formatData{
+		PromptData: data,
+		Spec:       Style(data.Style).spec(),
+		Vocabulary: strings.Join(vocabulary, "、"),
+	})
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("render prompt %s: %w", name, err)
+	}
+	return llm.Message{Role: llm.ROLE_SYSTEM, Content: b.String()}, nil
+}
+
+func parsePrompt(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse prompt %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// systemPrompt renders the prompt the article asks for. An unknown template
+// falls back to the default one, so a typo in Notion does not fail every post.
+func (s *ChatSummarizer) systemPrompt(article Article) (llm.Message, error) {
+	prompts := s.Prompts
+	if prompts == nil {
+		prompts = NewPrompts()
+	}
+	data := article.promptData()
+	data.Language = s.language(article)
+	data.Style = string(s.style(article))
+
+	system, err := prompts.System(article.Template, data, article.Vocabulary)
+	if errors.Is(err, ErrUnknownPrompt) {
+		log.Printf("%v, use the default prompt for %s\n", err, article.Link)
+		system, err = prompts.System(DefaultPrompt, data, article.Vocabulary)
+	}
+	return system, err
+}
+
+// style is the style the article asks for, or else the summarizer's.
+func (s *ChatSummarizer) style(article Article) Style {
+	switch {
+	case article.Style != "":
+		return article.Style
+	case s.Style != "":
+		return s.Style
+	default:
+		return DefaultStyle
+	}
+}
+
+// language is the language the article asks for, or else the summarizer's.
+func (s *ChatSummarizer) language(article Article) string {
+	switch {
+	case article.Language != "":
+		return article.Language
+	case s.Language != "":
+		return s.Language
+	default:
+		return DefaultLanguage
+	}
+}
@@ -0,0 +1,143 @@
+package kimi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"notion-summary/config"
+	"notion-summary/llm"
+	"strings"
+	"text/template"
+)
+
+// scoreTextTokens is how much of the article is read to score it.
+const scoreTextTokens = 2000
+
+var ErrInvalidRelevance = errors.New("invalid relevance")
+
+// Relevance is how much an article matters to the team, from 0 to 100.
+type Relevance struct {
+	Score int `json:"score"`
+	// Reason is the one-sentence justification of the score.
+	Reason string `json:"reason"`
+}
+
+// RelevanceSchema is the JSON schema of Relevance sent with the request.
+var RelevanceSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"score": {"type": "integer"},
+		"reason": {"type": "string"}
+	},
+	"required": ["score", "reason"],
+	"additionalProperties": false
+}`)
+
+var relevancePrompt = template.Must(template.New("relevance").Parse(`角色
+你是一个帮团队筛选文章的小助手。用户会给出文章的链接、标题和正文的开头，你需要根据团队的兴趣画像判断文章与团队的相关度。
+
+团队的兴趣画像
+{{.Profile}}
+
+评分标准
+给出0到100的整数分数：与关注的主题高度相关给80以上，有一定关联给40到80，关系不大给40以下，属于不关注的主题或完全无关给10以下。
+
+输出格式
+只输出一个JSON对象，不要输出代码块标记或任何其他文字。JSON对象包含以下字段：
+- score：相关度分数，0到100的整数
+- reason：一句话说明打分的理由，使用{{.Language}}
+
+示例：
+{"score": 85, "reason": "理由"}
+`))
+
+// ConfigProfile renders the interests and the ignored topics of conf as the
+// text of an interest profile, empty when both are.
+func ConfigProfile(conf config.RelevanceConf) string {
+	var b strings.Builder
+	if len(conf.Interests) > 0 {
+		fmt.Fprintf(&b, "关注的主题：%s\n", strings.Join(conf.Interests, "、"))
+	}
+	if len(conf.Ignore) > 0 {
+		fmt.Fprintf(&b, "不关注的主题：%s\n", strings.Join(conf.Ignore, "、"))
+	}
+	return b.String()
+}
+
+// ParseRelevance decodes and validates the model's answer, repairing the
+// usual slips first.
+func ParseRelevance(content string) (*Relevance, error) {
+	relevance := &Relevance{}
+	err := decodeAnswer(content, relevance)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRelevance, err)
+	}
+
+	relevance.Reason = strings.TrimSpace(relevance.Reason)
+	if relevance.Score < 0 || relevance.Score > 100 {
+		return nil, fmt.Errorf("%w: score %d is not between 0 and 100", ErrInvalidRelevance, relevance.Score)
+	}
+	if relevance.Reason == "" {
+		return nil, fmt.Errorf("%w: reason is empty", ErrInvalidRelevance)
+	}
+	return relevance, nil
+}
+
+// Score rates the article against its interest profile from its title and
+// the beginning of its text. It returns nil without a profile, and when the
+// answer stays invalid, so the article is summarized anyway.
+func (s *ChatSummarizer) Score(ctx context.Context, article Article) (*Relevance, error) {
+	if strings.TrimSpace(article.Profile) == "" {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	err := relevancePrompt.Execute(&b, struct{ Profile, Language string }{article.Profile, s.language(article)})
+	if err != nil {
+		return nil, err
+	}
+	system := llm.Message{Role: llm.ROLE_SYSTEM, Content: b.String()}
+
+	if chunks := splitChunks(article.Text, scoreTextTokens); len(chunks) > 1 {
+		article.Text = chunks[0]
+	}
+	prompt := article.Prompt()
+	model := ""
+	if len(s.Models) > 0 {
+		picked, ok := s.pickModel(system, prompt)
+		if !ok {
+			picked = s.Models[len(s.Models)-1]
+		}
+		model = picked.Name
+	}
+
+	var relevance *Relevance
+	err = s.ask(ctx, model, system, prompt, RelevanceSchema, func(content string) (err error) {
+		relevance, err = ParseRelevance(content)
+		return err
+	})
+	if errors.Is(err, ErrInvalidRelevance) {
+		log.Printf("score %s error:%v, summarize it anyway\n", article.Link, err)
+		return nil, nil
+	}
+	return relevance, err
+}
+
+// belowThreshold is the summary of an article skipped for its relevance, it
+// only carries the title and the score.
+func belowThreshold(article Article, relevance *Relevance) *Summary {
+	title := article.Title
+	if title == "" {
+		title = article.Link
+	}
+	return &Summary{
+		Title:          title,
+		Outline:        relevance.Reason,
+		Tags:           []string{},
+		KeyPoints:      []string{},
+		Relevance:      relevance,
+		BelowThreshold: true,
+	}
+}
@@ -0,0 +1,96 @@
+package kimi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Style is how much and what kind of summary is asked for.
+type Style string
+
+const (
+	StyleTLDR      Style = "tldr"
+	StyleBullets   Style = "bullets"
+	StyleDetailed  Style = "detailed"
+	StyleTechnical Style = "technical"
+)
+
+// DefaultStyle is the style of summaries that do not pick one.
+const DefaultStyle = StyleDetailed
+
+var ErrUnknownStyle = errors.New("unknown summary style")
+
+// styleSpec is what the output format asks of every field in a style.
+type styleSpec struct {
+	Label     string
+	Note      string
+	Outline   string
+	Summary   string
+	KeyPoints string
+}
+
+var styles = map[Style]styleSpec{
+	StyleTLDR: {
+		Label:     "TL;DR",
+		Note:      "用一句话讲清文章最重要的结论，越短越好。",
+		Outline:   "一句话的TL;DR，不超过50字",
+		Summary:   "一到两句话，补充TL;DR中没有提到的关键信息",
+		KeyPoints: "字符串数组，一到三条最关键的要点",
+	},
+	StyleBullets: {
+		Label:     "要点",
+		Note:      "读者只看要点，要点要具体、独立成句，不要重复。",
+		Outline:   "一句话的概要",
+		Summary:   "一段话的简短总结，不超过150字",
+		KeyPoints: "字符串数组，正好五条，按重要性排序，每条一句话",
+	},
+	StyleDetailed: {
+		Label:     "详细",
+		Outline:   "简单的概要，一到三句话",
+		Summary:   "详细的总结，使用markdown格式，要尽可能覆盖文章大部份的要点",
+		KeyPoints: "字符串数组，文章的关键要点，三到八条，每条一句话",
+	},
+	StyleTechnical: {
+		Label:     "技术",
+		Note:      "读者是工程师，关注文章中的技术细节：涉及的API、函数与配置项，软件及依赖的版本号，代码或接口的变更、不兼容的改动与迁移方式。原文没有的细节不要编造。",
+		Outline:   "一到两句话，说明文章涉及的技术与最重要的变化",
+		Summary:   "技术细节的总结，使用markdown格式，按API、版本、代码变更等分小节，API与代码用行内代码或代码块标出",
+		KeyPoints: "字符串数组，三到八条，每条是一个具体的API、版本或代码变更",
+	},
+}
+
+// Styles lists the styles from the shortest to the most detailed.
+func Styles() []Style {
+	return []Style{StyleTLDR, StyleBullets, StyleDetailed, StyleTechnical}
+}
+
+// ParseStyle accepts the name or the label of a style, ignoring case. An
+// empty name is an empty style, which means the default.
+func ParseStyle(name string) (Style, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	for _, style := range Styles() {
+		if strings.EqualFold(name, string(style)) || strings.EqualFold(name, styles[style].Label) {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownStyle, name)
+}
+
+// Label is the name of the style shown to readers.
+func (s Style) Label() string {
+	if spec, ok := styles[s]; ok {
+		return spec.Label
+	}
+	return string(s)
+}
+
+func (s Style) spec() styleSpec {
+	if spec, ok := styles[s]; ok {
+		return spec
+	}
+	return styles[DefaultStyle]
+}
@@ -0,0 +1,173 @@
+package kimi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidSummary = errors.New("invalid summary")
+
+// Summary is the structured summary the model answers with.
+type Summary struct {
+	// Title is the title translated to the language of the summary.
+	Title   string `json:"title"`
+	Outline string `json:"outline"`
+	// Summary is the detailed summary in Markdown.
+	Summary   string   `json:"summary"`
+	KeyPoints []string `json:"key_points"`
+	Tags      []string `json:"tags"`
+	// Language is the ISO 639-1 code of the article's language.
+	Language string `json:"language"`
+	// Style is the style the summary was asked in, it is not part of the answer.
+	Style Style `json:"style,omitempty"`
+	// Relevance is the score of the article, nil when it was not scored.
+	Relevance *Relevance `json:"relevance,omitempty"`
+	// BelowThreshold marks an article scored too low to be summarized, only
+	// its title, outline and relevance are set.
+	BelowThreshold bool `json:"below_threshold,omitempty"`
+}
+
+// SummarySchema is the JSON schema of Summary sent with the request.
+var SummarySchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"title": {"type": "string"},
+		"outline": {"type": "string"},
+		"summary": {"type": "string"},
+		"key_points": {"type": "array", "items": {"type": "string"}},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"language": {"type": "string"}
+	},
+	"required": ["title", "outline", "summary", "key_points", "tags", "language"],
+	"additionalProperties": false
+}`)
+
+var (
+	codeFence      = regexp.MustCompile("(?s)^```[a-zA-Z]*\\s*(.*?)\\s*```$")
+	trailingCommas = regexp.MustCompile(`,\s*([}\]])`)
+)
+
+// ParseSummary decodes and validates the model's answer, repairing the usual
+// slips first.
+func ParseSummary(content string) (*Summary, error) {
+	summary := &Summary{}
+	err := decodeAnswer(content, summary)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSummary, err)
+	}
+
+	summary.normalize()
+	err = summary.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// decodeAnswer decodes the JSON object of the model's answer into v. It
+// repairs a Markdown code fence or text around the object, and trailing
+// commas.
+func decodeAnswer(content string, v any) error {
+	content = strings.TrimSpace(content)
+	if m := codeFence.FindStringSubmatch(content); m != nil {
+		content = m[1]
+	}
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return errors.New("no JSON object in the answer")
+	}
+	content = content[start : end+1]
+
+	err := json.Unmarshal([]byte(content), v)
+	if err != nil {
+		err = json.Unmarshal([]byte(trailingCommas.ReplaceAllString(content, "$1")), v)
+	}
+	return err
+}
+
+func (s *Summary) normalize() {
+	s.Title = strings.TrimSpace(s.Title)
+	s.Outline = strings.TrimSpace(s.Outline)
+	s.Summary = strings.TrimSpace(s.Summary)
+	s.Language = strings.ToLower(strings.TrimSpace(s.Language))
+	s.KeyPoints = cleanList(s.KeyPoints, false)
+	s.Tags = cleanList(s.Tags, true)
+}
+
+// cleanList trims the items and drops the empty ones, and with unique the
+// case-insensitive duplicates too.
+func cleanList(items []string, unique bool) []string {
+	cleaned := []string{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		item = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(item), "-*•"))
+		key := strings.ToLower(item)
+		if item == "" || (unique && seen[key]) {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, item)
+	}
+	return cleaned
+}
+
+// Validate reports every required field that is missing.
+func (s *Summary) Validate() error {
+	var errs []error
+	required := func(value, name string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is empty", name))
+		}
+	}
+	required(s.Title, "title")
+	required(s.Outline, "outline")
+	switch {
+	case s.BelowThreshold && s.Relevance == nil:
+		errs = append(errs, errors.New("relevance is missing"))
+	case !s.BelowThreshold:
+		required(s.Summary, "summary")
+		required(s.Language, "language")
+		if len(s.KeyPoints) == 0 {
+			errs = append(errs, errors.New("key_points is empty"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidSummary, errors.Join(errs...))
+	}
+	return nil
+}
+
+// JSON encodes the summary the way it is stored.
+func (s *Summary) JSON() string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// Markdown renders the summary for reading in a terminal or a message.
+func (s *Summary) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", s.Title)
+	if s.Relevance != nil {
+		fmt.Fprintf(&b, "> 相关度：%d/100，%s\n\n", s.Relevance.Score, s.Relevance.Reason)
+	}
+	if s.BelowThreshold {
+		b.WriteString("相关度低于阈值，没有生成总结。\n")
+		return b.String()
+	}
+	if s.Style != "" {
+		fmt.Fprintf(&b, "> 总结风格：%s\n\n", s.Style.Label())
+	}
+	fmt.Fprintf(&b, "### 概要\n%s\n\n### 要点\n", s.Outline)
+	for _, point := range s.KeyPoints {
+		fmt.Fprintf(&b, "- %s\n", point)
+	}
+	fmt.Fprintf(&b, "\n### 总结\n%s\n", s.Summary)
+	if len(s.Tags) > 0 {
+		fmt.Fprintf(&b, "\n标签：%s\n", strings.Join(s.Tags, "、"))
+	}
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package kimi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const validAnswer = `{"title": "标题", "outline": "概要", "summary": "总结", "key_points": ["要点一", "要点二"], "tags": ["Go"], "language": "EN"}`
+
+func TestParseSummary(t *testing.T) {
+	want := &Summary{
+		Title:     "标题",
+		Outline:   "概要",
+		Summary:   "总结",
+		KeyPoints: []string{"要点一", "要点二"},
+		Tags:      []string{"Go"},
+		Language:  "en",
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    *Summary
+	}{
+		{name: "plain", content: validAnswer, want: want},
+		{name: "code fence", content: "```json\n" + validAnswer + "\n```", want: want},
+		{name: "code fence without language", content: "```\n" + validAnswer + "\n```", want: want},
+		{name: "surrounding text", content: "好的，以下是总结：\n" + validAnswer + "\n希望对你有帮助。", want: want},
+		{
+			name:    "trailing commas",
+			content: `{"title": "标题", "outline": "概要", "summary": "总结", "key_points": ["要点一", "要点二",], "tags": ["Go",], "language": "en",}`,
+			want:    want,
+		},
+		{
+			name:    "list markers and duplicate tags",
+			content: `{"title": " 标题 ", "outline": "概要", "summary": "总结", "key_points": ["- 要点一", "", "* 要点二"], "tags": ["Go", "go", " "], "language": "en"}`,
+			want:    want,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSummary(tt.content)
+			if err != nil {
+				t.Fatalf("ParseSummary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSummary() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSummaryInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "empty", content: ""},
+		{name: "no object", content: "这篇文章讲的是Go。"},
+		{name: "broken JSON", content: `{"title": "标题", "outline": }`},
+		{name: "missing summary", content: `{"title": "标题", "outline": "概要", "key_points": ["要点"], "tags": [], "language": "en"}`},
+		{name: "missing language", content: `{"title": "标题", "outline": "概要", "summary": "总结", "key_points": ["要点"], "tags": []}`},
+		{name: "empty key points", content: `{"title": "标题", "outline": "概要", "summary": "总结", "key_points": [" "], "tags": [], "language": "en"}`},
+		{name: "blank title", content: `{"title": "  ", "outline": "概要", "summary": "总结", "key_points": ["要点"], "tags": [], "language": "en"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSummary(tt.content)
+			if !errors.Is(err, ErrInvalidSummary) {
+				t.Errorf("ParseSummary() error = %v, want %v", err, ErrInvalidSummary)
+			}
+		})
+	}
+}
+
+func TestDecodeAnswer(t *testing.T) {
+	type answer struct {
+		Score  int    `json:"score"`
+		Reason string `json:"reason"`
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    answer
+		wantErr bool
+	}{
+		{name: "plain", content: `{"score": 80, "reason": "相关"}`, want: answer{80, "相关"}},
+		{name: "code fence", content: "```json\n{\"score\": 80, \"reason\": \"相关\"}\n```", want: answer{80, "相关"}},
+		{name: "surrounding text", content: `分数如下 {"score": 80, "reason": "相关"} 以上`, want: answer{80, "相关"}},
+		{name: "trailing comma", content: `{"score": 80, "reason": "相关",}`, want: answer{80, "相关"}},
+		{name: "missing field", content: `{"score": 80}`, want: answer{Score: 80}},
+		{name: "no object", content: `80分`, wantErr: true},
+		{name: "closing brace only", content: `} {`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got answer
+			err := decodeAnswer(tt.content, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeAnswer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("decodeAnswer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package kimi
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// maxTags is how many tags a summary keeps.
+const maxTags = 5
+
+// summarySchema is SummarySchema with the tags restricted to vocabulary, or
+// SummarySchema itself when the tags are free.
+func summarySchema(vocabulary []string) json.RawMessage {
+	if len(vocabulary) == 0 {
+		return SummarySchema
+	}
+
+	var schema map[string]any
+	err := json.Unmarshal(SummarySchema, &schema)
+	if err != nil {
+		return SummarySchema
+	}
+	properties := schema["properties"].(map[string]any)
+	properties["tags"] = map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "string", "enum": vocabulary},
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return SummarySchema
+	}
+	return data
+}
+
+// matchTags keeps the tags found in vocabulary, ignoring case and spelled as
+// in vocabulary. Without a vocabulary every tag is kept. Commas are dropped
+// either way, Notion does not allow them in option names.
+func matchTags(tags, vocabulary []string) []string {
+	known := map[string]string{}
+	for _, tag := range vocabulary {
+		known[strings.ToLower(strings.TrimSpace(tag))] = tag
+	}
+
+	matched := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.ReplaceAll(tag, ",", " "))
+		key := strings.ToLower(tag)
+		if len(vocabulary) > 0 {
+			tag = known[key]
+		}
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		matched = append(matched, tag)
+		if len(matched) == maxTags {
+			break
+		}
+	}
+	return matched
+}
@@ -0,0 +1,34 @@
+package kimi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchTags(t *testing.T) {
+	vocabulary := []string{"Go", "Kubernetes", "AI", "数据库"}
+
+	tests := []struct {
+		name       string
+		tags       []string
+		vocabulary []string
+		want       []string
+	}{
+		{name: "vocabulary spelling", tags: []string{"go", "KUBERNETES", "ai"}, vocabulary: vocabulary, want: []string{"Go", "Kubernetes", "AI"}},
+		{name: "surrounding spaces", tags: []string{" Go ", "数据库 "}, vocabulary: vocabulary, want: []string{"Go", "数据库"}},
+		{name: "unknown tags dropped", tags: []string{"Rust", "Go", "前端"}, vocabulary: vocabulary, want: []string{"Go"}},
+		{name: "case-insensitive duplicates", tags: []string{"Go", "go", "GO"}, vocabulary: vocabulary, want: []string{"Go"}},
+		{name: "none matched", tags: []string{"Rust"}, vocabulary: vocabulary, want: []string{}},
+		{name: "free tags kept", tags: []string{"Rust", "rust", " WebAssembly "}, want: []string{"Rust", "WebAssembly"}},
+		{name: "commas dropped", tags: []string{"Go, Rust"}, want: []string{"Go  Rust"}},
+		{name: "at most maxTags", tags: []string{"a", "b", "c", "d", "e", "f"}, want: []string{"a", "b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchTags(tt.tags, tt.vocabulary)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchTags(%q, %q) = %q, want %q", tt.tags, tt.vocabulary, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"notion-summary/config"
+)
+
+const ROLE_SYSTEM = "system"
+const ROLE_USER = "user"
+const ROLE_ASSISTANT = "assistant"
+
+const (
+	PROVIDER_MOONSHOT = "moonshot"
+	PROVIDER_OPENAI   = "openai"
+	PROVIDER_OLLAMA   = "ollama"
+)
+
+var ErrEmptyMessages = errors.New("messages are empty")
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatRequest is a provider independent chat completion request.
+// An empty Model means the provider's configured model.
+type ChatRequest struct {
+	Model       string
+	Messages    []Message
+	Temperature float32
+	// JSONSchema asks for a JSON answer following the schema, as far as the
+	// provider supports it. Nil means a plain text answer.
+	JSONSchema json.RawMessage
+}
+
+type ChatResponse struct {
+	Model   string
+	Content string
+	Usage   Usage
+}
+
+// ChatProvider is implemented by every LLM backend that can answer a chat completion.
+type ChatProvider interface {
+	Name() string
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+}
+
+// NewChatProvider builds the provider selected by conf.Provider.
+func NewChatProvider(conf config.AIConf) (ChatProvider, error) {
+	switch conf.Provider {
+	case "", PROVIDER_MOONSHOT:
+		return NewMoonshotProvider(conf.KimiSecretKey, conf.KimiModel), nil
+	case PROVIDER_OPENAI:
+		if conf.OpenAIBaseURL == "" {
+			return nil, errors.New("openai provider requires a base url")
+		}
+		return NewOpenAIProvider(PROVIDER_OPENAI, conf.OpenAIBaseURL, conf.OpenAIAPIKey, conf.OpenAIModel), nil
+	case PROVIDER_OLLAMA:
+		return NewOllamaProvider(conf.OllamaBaseURL, conf.OllamaModel), nil
+	default:
+		return nil, fmt.Errorf("unknown ai provider: %s", conf.Provider)
+	}
+}
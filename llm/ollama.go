@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// OllamaProvider talks to a local Ollama style server through its /api/chat endpoint.
+type OllamaProvider struct {
+	BaseURL string
+	Model   string
+	Client  *http.Client
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []Message       `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Model           string  `json:"model"`
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error,omitempty"`
+}
+
+func NewOllamaProvider(baseURL, model string) *OllamaProvider {
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	return &OllamaProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Model:   model,
+		Client:  &http.Client{},
+	}
+}
+
+func (p *OllamaProvider) Name() string {
+	return PROVIDER_OLLAMA
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, chatReq ChatRequest) (*ChatResponse, error) {
+	if len(chatReq.Messages) == 0 {
+		return nil, ErrEmptyMessages
+	}
+
+	model := chatReq.Model
+	if model == "" {
+		model = p.Model
+	}
+	requestBody, err := json.Marshal(ollamaRequest{
+		Model:    model,
+		Messages: chatReq.Messages,
+		Format:   chatReq.JSONSchema,
+		Options:  map[string]any{"temperature": chatReq.Temperature},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL+"/api/chat", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("statusCode %d, request error: %v", resp.StatusCode, string(body))
+	}
+
+	respData := ollamaResponse{}
+	err = json.Unmarshal(body, &respData)
+	if err != nil {
+		return nil, err
+	}
+	if respData.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", respData.Error)
+	}
+
+	return &ChatResponse{
+		Model:   respData.Model,
+		Content: respData.Message.Content,
+		Usage: Usage{
+			PromptTokens:     respData.PromptEvalCount,
+			CompletionTokens: respData.EvalCount,
+			TotalTokens:      respData.PromptEvalCount + respData.EvalCount,
+		},
+	}, nil
+}
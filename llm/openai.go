@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const moonshotBaseURL = "https://api.moonshot.cn/v1"
+
+// OpenAIProvider talks to any endpoint implementing the OpenAI chat completions API.
+type OpenAIProvider struct {
+	name    string
+	BaseURL string
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+type openAIRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Temperature    float32         `json:"temperature"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict"`
+}
+
+type openAIResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   Usage          `json:"usage"`
+}
+
+type openAIChoice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+func NewOpenAIProvider(name, baseURL, apiKey, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		name:    name,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  &http.Client{},
+	}
+}
+
+// NewMoonshotProvider returns an OpenAI compatible provider pointed at Kimi.
+func NewMoonshotProvider(apiKey, model string) *OpenAIProvider {
+	return NewOpenAIProvider(PROVIDER_MOONSHOT, moonshotBaseURL, apiKey, model)
+}
+
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+// responseFormat asks for a JSON answer. Moonshot only takes json_object and
+// relies on the schema described in the prompt.
+func (p *OpenAIProvider) responseFormat(schema json.RawMessage) *responseFormat {
+	if schema == nil {
+		return nil
+	}
+	if p.name == PROVIDER_MOONSHOT {
+		return &responseFormat{Type: "json_object"}
+	}
+	return &responseFormat{
+		Type:       "json_schema",
+		JSONSchema: &jsonSchema{Name: "response", Schema: schema, Strict: true},
+	}
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, chatReq ChatRequest) (*ChatResponse, error) {
+	if len(chatReq.Messages) == 0 {
+		return nil, ErrEmptyMessages
+	}
+
+	model := chatReq.Model
+	if model == "" {
+		model = p.Model
+	}
+	requestBody, err := json.Marshal(openAIRequest{
+		Model:          model,
+		Messages:       chatReq.Messages,
+		Temperature:    chatReq.Temperature,
+		ResponseFormat: p.responseFormat(chatReq.JSONSchema),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := p.BaseURL + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+	if p.APIKey != "" {
+		req.Header.Add("Authorization", "Bearer "+p.APIKey)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("statusCode %d, request error: %v", resp.StatusCode, string(body))
+	}
+
+	respData := openAIResponse{}
+	err = json.Unmarshal(body, &respData)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ChatResponse{Model: respData.Model, Usage: respData.Usage}
+	if len(respData.Choices) > 0 {
+		result.Content = respData.Choices[0].Message.Content
+	}
+	return result, nil
+}
@@ -2,17 +2,13 @@ package main
 
 import (
 	"log"
-	"net/http"
-	"notion-summary/config"
-	"notion-summary/notion"
+	"notion-summary/cmd"
+	"os"
 )
 
 func main() {
-	log.Println("Initialize config")
-	config.InitConfig()
-
-	log.Println("Initialize cron jobs")
-	notion.InitCronJobs()
-
-	http.ListenAndServe(":"+config.Service.Port, nil)
+	err := cmd.Execute(os.Args[1:])
+	if err != nil {
+		log.Fatalln(err)
+	}
 }
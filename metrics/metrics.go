@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "notion_summary"
+
+// Post stages counted by Posts.
+const (
+	StageDiscovered = "discovered"
+	StageDeduped    = "deduped"
+	StageSummarized = "summarized"
+	StageSaved      = "saved"
+)
+
+var (
+	FeedFetches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feed_fetches_total",
+		Help:      "RSS feed fetches by subscription and result.",
+	}, []string{"subscription", "result"})
+
+	Posts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_total",
+		Help:      "Posts by pipeline stage: discovered in feeds, deduped as already handled, summarized and saved to Notion.",
+	}, []string{"stage"})
+
+	PostFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "post_failures_total",
+		Help:      "Posts that failed to be summarized or saved to Notion.",
+	}, []string{"stage"})
+
+	AIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ai_request_duration_seconds",
+		Help:      "Latency of chat requests to the AI provider.",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 40, 80, 160},
+	}, []string{"provider"})
+
+	AITokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_tokens_total",
+		Help:      "Tokens used by chat requests, by provider and type (prompt or completion).",
+	}, []string{"provider", "type"})
+
+	AIErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_errors_total",
+		Help:      "Failed chat requests to the AI provider.",
+	}, []string{"provider"})
+
+	NotionRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notion_requests_total",
+		Help:      "Requests to the Notion API by endpoint and HTTP status, status is \"error\" when no response arrived.",
+	}, []string{"endpoint", "status"})
+
+	Retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retries_total",
+		Help:      "Retried operations by component: feed, ai or notion.",
+	}, []string{"component"})
+
+	LastSuccessfulSync = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last sync that finished without error.",
+	})
+)
+
+// ObserveNotionRequest counts one attempt of a Notion request, it matches
+// the request hook of the Notion client.
+func ObserveNotionRequest(method, endpoint string, status int, attempt uint) {
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	NotionRequests.WithLabelValues(method+" "+endpoint, code).Inc()
+	if attempt > 0 {
+		Retries.WithLabelValues("notion").Inc()
+	}
+}
+
+// ObserveChat records the latency and token usage of a chat request.
+func ObserveChat(provider string, start time.Time, promptTokens, completionTokens int, err error) {
+	AIRequestDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	if err != nil {
+		AIErrors.WithLabelValues(provider).Inc()
+		return
+	}
+	AITokens.WithLabelValues(provider, "prompt").Add(float64(promptTokens))
+	AITokens.WithLabelValues(provider, "completion").Add(float64(completionTokens))
+}
@@ -0,0 +1,60 @@
+package notification
+
+import (
+	"bytes"
+	"html/template"
+	"notion-summary/config"
+	"time"
+)
+
+// DigestPost is one post rendered by the digest template.
+type DigestPost struct {
+	Link        string
+	Title       string
+	BlogAddr    string
+	Author      string
+	PublishTime time.Time
+	Summary     string
+}
+
+type digestData struct {
+	Posts []DigestPost
+}
+
+// RenderDigest renders the posts through the HTML template at templatePath.
+func RenderDigest(templatePath string, posts []DigestPost) (string, error) {
+	tmpl, err := template.ParseFiles(templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, digestData{Posts: posts})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// DigestEnabled reports whether any digest recipient is configured.
+func DigestEnabled() bool {
+	return len(config.Email.Recipients) > 0
+}
+
+// SendDigest emails the posts to the configured recipients.
+func SendDigest(posts []DigestPost) error {
+	if !DigestEnabled() || len(posts) == 0 {
+		return nil
+	}
+
+	html, err := RenderDigest(config.Email.TemplatePath, posts)
+	if err != nil {
+		return err
+	}
+
+	mailer, err := NewMailer(config.Email)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(config.Email.Subject, config.Email.Recipients, html)
+}
@@ -0,0 +1,96 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DingTalkNotifier posts a Markdown message to a DingTalk group robot.
+// Secret enables the robot's signature verification.
+type DingTalkNotifier struct {
+	name       string
+	WebhookURL string
+	Secret     string
+}
+
+func (n *DingTalkNotifier) Name() string {
+	return n.name
+}
+
+func (n *DingTalkNotifier) Notify(ctx context.Context, posts []Notification) error {
+	webhookURL := n.WebhookURL
+	if n.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(n.Secret))
+		mac.Write([]byte(timestamp + "\n" + n.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		sep := "?"
+		if strings.Contains(webhookURL, "?") {
+			sep = "&"
+		}
+		webhookURL += sep + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+	}
+
+	body := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": "Blog Updates",
+			"text":  chatMarkdown(posts, 800),
+		},
+	}
+	return postJSON(ctx, webhookURL, body, checkErrCode("errcode"))
+}
+
+// WeComNotifier posts a Markdown message to a WeCom group robot.
+type WeComNotifier struct {
+	name       string
+	WebhookURL string
+}
+
+// WeCom rejects Markdown content longer than 4096 bytes.
+const weComMaxContent = 4096
+
+func (n *WeComNotifier) Name() string {
+	return n.name
+}
+
+func (n *WeComNotifier) Notify(ctx context.Context, posts []Notification) error {
+	for _, post := range posts {
+		content := chatMarkdown([]Notification{post}, 600)
+		for len(content) > weComMaxContent {
+			runes := []rune(content)
+			content = string(runes[:len(runes)*9/10])
+		}
+
+		body := map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": content},
+		}
+		err := postJSON(ctx, n.WebhookURL, body, checkErrCode("errcode"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chatMarkdown renders the posts in the Markdown subset DingTalk and WeCom share.
+func chatMarkdown(posts []Notification, outlineLimit int) string {
+	var sb strings.Builder
+	for i, post := range posts {
+		if i > 0 {
+			sb.WriteString("\n\n---\n\n")
+		}
+		fmt.Fprintf(&sb, "### [%s](%s)\n", post.displayTitle(), post.Link)
+		fmt.Fprintf(&sb, "> %s · %s\n\n", post.Subscription, post.Authors)
+		sb.WriteString(truncate(post.Outline, outlineLimit))
+	}
+	return sb.String()
+}
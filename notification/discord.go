@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+// Discord accepts at most ten embeds per message.
+const discordMaxEmbeds = 10
+
+// DiscordNotifier posts embeds to a Discord channel webhook.
+type DiscordNotifier struct {
+	name       string
+	WebhookURL string
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Author      *discordAuthor `json:"author,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordAuthor struct {
+	Name string `json:"name"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+func (n *DiscordNotifier) Name() string {
+	return n.name
+}
+
+func (n *DiscordNotifier) Notify(ctx context.Context, posts []Notification) error {
+	for start := 0; start < len(posts); start += discordMaxEmbeds {
+		end := min(start+discordMaxEmbeds, len(posts))
+
+		var embeds []discordEmbed
+		for _, post := range posts[start:end] {
+			embed := discordEmbed{
+				Title:       truncate(post.displayTitle(), 256),
+				URL:         post.Link,
+				Description: truncate(post.Outline, 2000),
+				Footer:      &discordFooter{Text: post.Subscription},
+			}
+			if post.Authors != "" {
+				embed.Author = &discordAuthor{Name: truncate(post.Authors, 256)}
+			}
+			if !post.PublishTime.IsZero() {
+				embed.Timestamp = post.PublishTime.Format(time.RFC3339)
+			}
+			embeds = append(embeds, embed)
+		}
+
+		err := postJSON(ctx, n.WebhookURL, map[string]any{"embeds": embeds}, checkStatus)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,24 +1,102 @@
 package notification
 
 import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
 	"notion-summary/config"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/resend/resend-go/v2"
 )
 
-func SendEmail(subject string, to string, content string) error {
-	resendClient := resend.NewClient(config.Email.APIKey)
+// Mailer delivers an HTML email to the recipients.
+type Mailer interface {
+	Send(subject string, to []string, html string) error
+}
+
+// NewMailer returns the transport selected by conf.Transport.
+func NewMailer(conf config.EmailConf) (Mailer, error) {
+	switch conf.Transport {
+	case "", "resend":
+		return &ResendMailer{APIKey: conf.APIKey, From: conf.FROM}, nil
+	case "smtp":
+		return &SMTPMailer{
+			Host:     conf.SMTPHost,
+			Port:     conf.SMTPPort,
+			Username: conf.SMTPUsername,
+			Password: conf.SMTPPassword,
+			From:     conf.FROM,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown email transport: %s", conf.Transport)
+	}
+}
+
+type ResendMailer struct {
+	APIKey string
+	From   string
+}
+
+func (m *ResendMailer) Send(subject string, to []string, html string) error {
+	resendClient := resend.NewClient(m.APIKey)
 	params := &resend.SendEmailRequest{
-		From:    config.Email.FROM,
-		To:      []string{to},
+		From:    m.From,
+		To:      to,
 		Subject: subject,
-		Html:    content,
+		Html:    html,
 	}
 
 	_, err := resendClient.Emails.Send(params)
 	return err
 }
 
+// SMTPMailer sends through a plain SMTP server, STARTTLS is used when the
+// server offers it and authentication only when a username is configured.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(subject string, to []string, html string) error {
+	if len(to) == 0 {
+		return errors.New("no recipients")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + m.From + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(html)
+
+	addr := m.Host + ":" + strconv.Itoa(m.Port)
+	return smtp.SendMail(addr, auth, m.From, to, []byte(msg.String()))
+}
+
+func SendEmail(subject string, to string, content string) error {
+	mailer, err := NewMailer(config.Email)
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(subject, []string{to}, content)
+}
+
 func SendMoreEmails(subject string, toSends map[string]string) error {
 	for to, content := range toSends {
 		err := SendEmail(subject, to, content)
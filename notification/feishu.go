@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// FeishuNotifier posts a rich text message to a Feishu/Lark custom bot.
+// Secret enables the bot's signature verification.
+type FeishuNotifier struct {
+	name       string
+	WebhookURL string
+	Secret     string
+}
+
+type feishuElement struct {
+	Tag  string `json:"tag"`
+	Text string `json:"text"`
+	Href string `json:"href,omitempty"`
+}
+
+func (n *FeishuNotifier) Name() string {
+	return n.name
+}
+
+func (n *FeishuNotifier) Notify(ctx context.Context, posts []Notification) error {
+	var content [][]feishuElement
+	for _, post := range posts {
+		content = append(content,
+			[]feishuElement{{Tag: "a", Text: post.displayTitle(), Href: post.Link}},
+			[]feishuElement{{Tag: "text", Text: post.Subscription + " · " + post.Authors}},
+			[]feishuElement{{Tag: "text", Text: truncate(post.Outline, 1000)}},
+			[]feishuElement{{Tag: "text", Text: ""}},
+		)
+	}
+
+	body := map[string]any{
+		"msg_type": "post",
+		"content": map[string]any{
+			"post": map[string]any{
+				"zh_cn": map[string]any{
+					"title":   "Blog Updates",
+					"content": content,
+				},
+			},
+		},
+	}
+	if n.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		body["timestamp"] = timestamp
+		body["sign"] = feishuSign(timestamp, n.Secret)
+	}
+
+	return postJSON(ctx, n.WebhookURL, body, checkErrCode("code"))
+}
+
+func feishuSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,137 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"notion-summary/config"
+	"strings"
+	"time"
+)
+
+// Notification is a newly summarized post as shown in chat messages.
+type Notification struct {
+	Subscription string
+	Title        string
+	CNTitle      string
+	Outline      string
+	Link         string
+	Authors      string
+	PublishTime  time.Time
+}
+
+// Notifier delivers a batch of newly summarized posts to one chat channel.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, posts []Notification) error
+}
+
+// NewNotifiers builds every configured channel, keyed by its name.
+func NewNotifiers(confs []config.NotifierConf) (map[string]Notifier, error) {
+	notifiers := map[string]Notifier{}
+	for _, conf := range confs {
+		notifier, err := NewNotifier(conf)
+		if err != nil {
+			return nil, err
+		}
+		notifiers[conf.Name] = notifier
+	}
+	return notifiers, nil
+}
+
+func NewNotifier(conf config.NotifierConf) (Notifier, error) {
+	switch conf.Type {
+	case "slack":
+		return &SlackNotifier{name: conf.Name, WebhookURL: conf.WebhookURL}, nil
+	case "discord":
+		return &DiscordNotifier{name: conf.Name, WebhookURL: conf.WebhookURL}, nil
+	case "telegram":
+		return &TelegramNotifier{name: conf.Name, BotToken: conf.BotToken, ChatID: conf.ChatID}, nil
+	case "feishu":
+		return &FeishuNotifier{name: conf.Name, WebhookURL: conf.WebhookURL, Secret: conf.Secret}, nil
+	case "dingtalk":
+		return &DingTalkNotifier{name: conf.Name, WebhookURL: conf.WebhookURL, Secret: conf.Secret}, nil
+	case "wecom":
+		return &WeComNotifier{name: conf.Name, WebhookURL: conf.WebhookURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q of %s", conf.Type, conf.Name)
+	}
+}
+
+// displayTitle prefers the translated title and keeps the original next to it.
+func (n Notification) displayTitle() string {
+	if n.CNTitle == "" || n.CNTitle == n.Title {
+		return n.Title
+	}
+	return n.CNTitle + "（" + n.Title + "）"
+}
+
+// truncate cuts s to at most limit runes, marking the cut with an ellipsis.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+var webhookClient = &http.Client{Timeout: 15 * time.Second}
+
+// postJSON posts body to url and hands the response to check, which returns
+// an error when the channel reports a failure.
+func postJSON(ctx context.Context, url string, body any, check func(statusCode int, respBody []byte) error) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return check(resp.StatusCode, respBody)
+}
+
+// checkStatus accepts any 2xx response.
+func checkStatus(statusCode int, respBody []byte) error {
+	if statusCode < 200 || statusCode >= 300 {
+		return fmt.Errorf("statusCode %d, request error: %s", statusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// checkErrCode accepts 2xx responses whose JSON body carries a zero error code
+// in the named field, as the Feishu, DingTalk and WeCom robots return.
+func checkErrCode(field string) func(int, []byte) error {
+	return func(statusCode int, respBody []byte) error {
+		err := checkStatus(statusCode, respBody)
+		if err != nil {
+			return err
+		}
+
+		result := map[string]any{}
+		err = json.Unmarshal(respBody, &result)
+		if err != nil {
+			return err
+		}
+		if code, _ := result[field].(float64); code != 0 {
+			return fmt.Errorf("webhook error: %s", string(respBody))
+		}
+		return nil
+	}
+}
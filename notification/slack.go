@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SlackNotifier posts to a Slack incoming webhook.
+type SlackNotifier struct {
+	name       string
+	WebhookURL string
+}
+
+func (n *SlackNotifier) Name() string {
+	return n.name
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, posts []Notification) error {
+	var sb strings.Builder
+	for i, post := range posts {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "*<%s|%s>*\n", post.Link, slackEscape(post.displayTitle()))
+		fmt.Fprintf(&sb, "_%s · %s_\n", slackEscape(post.Subscription), slackEscape(post.Authors))
+		sb.WriteString(slackEscape(truncate(post.Outline, 600)))
+	}
+
+	body := map[string]any{"text": sb.String()}
+	return postJSON(ctx, n.WebhookURL, body, checkStatus)
+}
+
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,56 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+// TelegramNotifier sends one message per post through the Telegram bot API.
+type TelegramNotifier struct {
+	name     string
+	BotToken string
+	ChatID   string
+}
+
+func (n *TelegramNotifier) Name() string {
+	return n.name
+}
+
+func (n *TelegramNotifier) Notify(ctx context.Context, posts []Notification) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPI, n.BotToken)
+	for _, post := range posts {
+		text := fmt.Sprintf("<b><a href=\"%s\">%s</a></b>\n<i>%s · %s</i>\n\n%s",
+			html.EscapeString(post.Link),
+			html.EscapeString(post.displayTitle()),
+			html.EscapeString(post.Subscription),
+			html.EscapeString(post.Authors),
+			html.EscapeString(truncate(post.Outline, 3000)))
+		body := map[string]any{
+			"chat_id":                  n.ChatID,
+			"text":                     text,
+			"parse_mode":               "HTML",
+			"disable_web_page_preview": true,
+		}
+
+		err := postJSON(ctx, url, body, checkTelegram)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkTelegram(statusCode int, respBody []byte) error {
+	result := struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}{}
+	if json.Unmarshal(respBody, &result) == nil && !result.OK {
+		return fmt.Errorf("telegram error: %s", result.Description)
+	}
+	return checkStatus(statusCode, respBody)
+}
@@ -2,19 +2,134 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log"
 	"net/http"
-	"notion-summary/config"
+	"strings"
 	"time"
 
 	"github.com/avast/retry-go"
+	"golang.org/x/time/rate"
 )
 
-func makeRequest(method string, url string, reqParams, respStruct interface{}) (err error) {
+const (
+	DefaultBaseURL = "https://api.notion.com"
+	DefaultVersion = "2022-06-28"
+
+	// Notion allows an average of about three requests per second per integration.
+	defaultRequestsPerSecond = 3
+)
+
+// Client talks to one Notion workspace. It is safe for concurrent use, and all
+// requests made through it share one rate limiter.
+type Client struct {
+	baseURL    string
+	token      string
+	version    string
+	httpClient *http.Client
+	timeout    time.Duration
+	logger     *log.Logger
+	limiter    *rate.Limiter
+	hook       RequestHook
+}
+
+type Option func(*Client)
+
+// RequestHook observes every attempt of a request. endpoint is the path with
+// the id replaced by ":id", status is 0 when no response was received and
+// attempt counts from 0.
+type RequestHook func(method, endpoint string, status int, attempt uint)
+
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+func WithVersion(version string) Option {
+	return func(c *Client) {
+		c.version = version
+	}
+}
+
+// WithHTTPClient sends the requests through httpClient, a nil one is ignored.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithTimeout bounds every single HTTP request, retries get a fresh timeout.
+// It applies to the client of WithHTTPClient too, whatever the order of the
+// options, without changing the caller's client.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+func WithRateLimit(requestsPerSecond int) Option {
+	return func(c *Client) {
+		if requestsPerSecond > 0 {
+			c.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), requestsPerSecond)
+		}
+	}
+}
+
+func WithRequestHook(hook RequestHook) Option {
+	return func(c *Client) {
+		c.hook = hook
+	}
+}
+
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		baseURL:    DefaultBaseURL,
+		version:    DefaultVersion,
+		httpClient: &http.Client{},
+		logger:     log.Default(),
+		limiter:    rate.NewLimiter(defaultRequestsPerSecond, defaultRequestsPerSecond),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.timeout > 0 {
+		httpClient := *c.httpClient
+		httpClient.Timeout = c.timeout
+		c.httpClient = &httpClient
+	}
+	return c
+}
+
+func (c *Client) makeRequest(ctx context.Context, method string, path string, reqParams, respStruct interface{}) (err error) {
+	url := c.baseURL + path
+	idempotent := isIdempotent(method, path)
+	var attempt uint
 	return retry.Do(func() error {
+		defer func() { attempt++ }()
+
+		err := c.limiter.Wait(ctx)
+		if err != nil {
+			return err
+		}
+
 		var reqBody []byte
 		if reqParams != nil {
 			reqBody, err = json.Marshal(reqParams)
@@ -22,41 +137,72 @@ func makeRequest(method string, url string, reqParams, respStruct interface{}) (
 				return err
 			}
 		}
-		req, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(reqBody))
 		if err != nil {
 			return err
 		}
 
-		req.Header.Add("Authorization", "Bearer "+config.Notion.NotionApiKey)
-		req.Header.Add("Notion-Version", "2022-06-28")
+		req.Header.Add("Authorization", "Bearer "+c.token)
+		req.Header.Add("Notion-Version", c.version)
 		req.Header.Add("Content-Type", "application/json")
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			log.Println("Error making request:", err)
+			c.logger.Println("Error making request:", err)
+			c.observe(method, path, 0, attempt)
 			return err
 		}
 		defer resp.Body.Close()
+		c.observe(method, path, resp.StatusCode, attempt)
 
 		respBody, err := io.ReadAll(resp.Body)
 		if err != nil {
-			log.Println("Error reading response:", err)
+			c.logger.Println("Error reading response:", err)
 			return err
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			err = fmt.Errorf("got error:%s", string(respBody))
-			return err
+			return newAPIError(resp, respBody)
 		}
 
 		return json.Unmarshal(respBody, respStruct)
 	},
+		retry.Context(ctx),
 		retry.Attempts(5),
 		retry.Delay(2*time.Second),
-		retry.DelayType(retry.BackOffDelay),
+		retry.DelayType(retryAfterDelay),
+		retry.RetryIf(func(err error) bool {
+			return isRetryable(err, idempotent)
+		}),
+		retry.LastErrorOnly(true),
 		retry.OnRetry(func(n uint, err error) {
-			log.Printf("Retry #%d to %s due to error: %s\n", n, url, err)
+			c.logger.Printf("Retry #%d to %s due to error: %s\n", n, url, err)
 		}),
 	)
 }
+
+func (c *Client) observe(method, path string, status int, attempt uint) {
+	if c.hook != nil {
+		c.hook(method, endpointOf(path), status, attempt)
+	}
+}
+
+// endpointOf drops the query and replaces the id in paths like
+// /v1/databases/{id}/query, so the endpoint has a bounded set of values.
+func endpointOf(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	if len(segments) > 3 {
+		segments[3] = ":id"
+	}
+	return strings.Join(segments, "/")
+}
+
+// retryAfterDelay waits as long as Notion asks through Retry-After, and backs off otherwise.
+func retryAfterDelay(n uint, err error, conf *retry.Config) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	return retry.BackOffDelay(n, err, conf)
+}
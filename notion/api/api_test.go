@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewClientTimeout(t *testing.T) {
+	custom := &http.Client{}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want time.Duration
+	}{
+		{"timeout only", []Option{WithTimeout(time.Second)}, time.Second},
+		{"timeout before client", []Option{WithTimeout(time.Second), WithHTTPClient(custom)}, time.Second},
+		{"timeout after client", []Option{WithHTTPClient(custom), WithTimeout(time.Second)}, time.Second},
+		{"nil client ignored", []Option{WithHTTPClient(nil), WithTimeout(time.Second)}, time.Second},
+		{"no timeout", []Option{WithHTTPClient(custom)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(tt.opts...)
+			if c.httpClient == nil {
+				t.Fatal("http client is nil")
+			}
+			if c.httpClient.Timeout != tt.want {
+				t.Errorf("timeout = %v, want %v", c.httpClient.Timeout, tt.want)
+			}
+		})
+	}
+	if custom.Timeout != 0 {
+		t.Errorf("the caller's client was changed, timeout = %v", custom.Timeout)
+	}
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 type BlockChildResponse struct {
@@ -13,12 +17,22 @@ type BlockChildResponse struct {
 }
 
 type Block struct {
-	Object    string          `json:"object,omitempty"`
-	ID        string          `json:"id,omitempty"`
-	Type      string          `json:"type,omitempty"`
-	Bookmark  *BlockBookmark  `json:"bookmark,omitempty"`
-	Heading2  *BlockHeading2  `json:"heading_2,omitempty"`
-	Paragraph *BlockParagraph `json:"paragraph,omitempty"`
+	Object           string          `json:"object,omitempty"`
+	ID               string          `json:"id,omitempty"`
+	Type             string          `json:"type,omitempty"`
+	HasChildren      bool            `json:"has_children,omitempty"`
+	Bookmark         *BlockBookmark  `json:"bookmark,omitempty"`
+	Heading1         *BlockHeading   `json:"heading_1,omitempty"`
+	Heading2         *BlockHeading   `json:"heading_2,omitempty"`
+	Heading3         *BlockHeading   `json:"heading_3,omitempty"`
+	Paragraph        *BlockParagraph `json:"paragraph,omitempty"`
+	BulletedListItem *BlockListItem  `json:"bulleted_list_item,omitempty"`
+	NumberedListItem *BlockListItem  `json:"numbered_list_item,omitempty"`
+	Quote            *BlockQuote     `json:"quote,omitempty"`
+	Code             *BlockCode      `json:"code,omitempty"`
+	Divider          *BlockDivider   `json:"divider,omitempty"`
+	Callout          *BlockCallout   `json:"callout,omitempty"`
+	Toggle           *BlockToggle    `json:"toggle,omitempty"`
 }
 
 type BlockBookmark struct {
@@ -26,22 +40,153 @@ type BlockBookmark struct {
 	URL     string             `json:"url,omitempty"`
 }
 
-type BlockHeading2 struct {
+type BlockHeading struct {
 	RichText []RichTextProperty `json:"rich_text,omitempty"`
 }
 
 type BlockParagraph struct {
 	RichText []RichTextProperty `json:"rich_text,omitempty"`
+	Children []Block            `json:"children,omitempty"`
 }
 
-func FetchBlockChilds(blockID string) ([]Block, error) {
-	url := fmt.Sprintf("https://api.notion.com/v1/blocks/%s/children", blockID)
-	blockChild := &BlockChildResponse{}
+type BlockListItem struct {
+	RichText []RichTextProperty `json:"rich_text,omitempty"`
+	Children []Block            `json:"children,omitempty"`
+}
+
+type BlockQuote struct {
+	RichText []RichTextProperty `json:"rich_text,omitempty"`
+	Children []Block            `json:"children,omitempty"`
+}
 
-	err := makeRequest(http.MethodGet, url, nil, blockChild)
+type BlockCode struct {
+	RichText []RichTextProperty `json:"rich_text,omitempty"`
+	Language string             `json:"language,omitempty"`
+}
+
+type BlockDivider struct{}
+
+type BlockCallout struct {
+	RichText []RichTextProperty `json:"rich_text,omitempty"`
+	Icon     *Icon              `json:"icon,omitempty"`
+	Color    string             `json:"color,omitempty"`
+	Children []Block            `json:"children,omitempty"`
+}
+
+type BlockToggle struct {
+	RichText []RichTextProperty `json:"rich_text,omitempty"`
+	Children []Block            `json:"children,omitempty"`
+}
+
+type Icon struct {
+	Type  string `json:"type,omitempty"`
+	Emoji string `json:"emoji,omitempty"`
+}
+
+// RichText returns the rich text of the block whatever its type.
+func (b Block) RichText() []RichTextProperty {
+	switch {
+	case b.Heading1 != nil:
+		return b.Heading1.RichText
+	case b.Heading2 != nil:
+		return b.Heading2.RichText
+	case b.Heading3 != nil:
+		return b.Heading3.RichText
+	case b.Paragraph != nil:
+		return b.Paragraph.RichText
+	case b.BulletedListItem != nil:
+		return b.BulletedListItem.RichText
+	case b.NumberedListItem != nil:
+		return b.NumberedListItem.RichText
+	case b.Quote != nil:
+		return b.Quote.RichText
+	case b.Code != nil:
+		return b.Code.RichText
+	case b.Callout != nil:
+		return b.Callout.RichText
+	case b.Toggle != nil:
+		return b.Toggle.RichText
+	default:
+		return nil
+	}
+}
+
+// PlainText concatenates the content of the rich text runs.
+func PlainText(richText []RichTextProperty) string {
+	var sb strings.Builder
+	for _, rt := range richText {
+		if rt.PlainText != "" {
+			sb.WriteString(rt.PlainText)
+		} else {
+			sb.WriteString(rt.Text.Content)
+		}
+	}
+	return sb.String()
+}
+
+// FetchBlockChilds returns all children of the block, following the cursor across pages.
+func (c *Client) FetchBlockChilds(ctx context.Context, blockID string) ([]Block, error) {
+	var blocks []Block
+	err := c.ForEachBlockChild(ctx, blockID, func(children []Block) error {
+		blocks = append(blocks, children...)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return blockChild.Results, nil
+	return blocks, nil
+}
+
+// ForEachBlockChild walks the children of the block page by page and hands every page to fn.
+// Iteration stops at the last page or as soon as fn returns an error.
+func (c *Client) ForEachBlockChild(ctx context.Context, blockID string, fn func(children []Block) error) error {
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("page_size", strconv.Itoa(MaxPageSize))
+		if cursor != "" {
+			query.Set("start_cursor", cursor)
+		}
+		path := fmt.Sprintf("/v1/blocks/%s/children?%s", blockID, query.Encode())
+
+		blockChild := &BlockChildResponse{}
+		err := c.makeRequest(ctx, http.MethodGet, path, nil, blockChild)
+		if err != nil {
+			return err
+		}
+
+		err = fn(blockChild.Results)
+		if err != nil {
+			return err
+		}
+
+		if !blockChild.HasMore || blockChild.NextCursor == "" {
+			return nil
+		}
+		cursor = blockChild.NextCursor
+	}
+}
+
+type AppendBlockChildrenRequest struct {
+	Children []Block `json:"children"`
+}
+
+// AppendBlockChildren appends the blocks to the block or page, split into as
+// many requests as Notion's size limits require.
+func (c *Client) AppendBlockChildren(ctx context.Context, blockID string, children []Block) error {
+	for _, batch := range batchBlocks(SplitBlocks(children)) {
+		err := c.appendBlockChildren(ctx, blockID, batch)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) appendBlockChildren(ctx context.Context, blockID string, children []Block) error {
+	path := fmt.Sprintf("/v1/blocks/%s/children", blockID)
+	reqBody := AppendBlockChildrenRequest{Children: children}
+
+	return c.makeRequest(ctx, http.MethodPatch, path, reqBody, &BlockChildResponse{})
 }
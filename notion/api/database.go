@@ -1,19 +1,23 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
 
 type DatabaseRequestBody struct {
-	Filter map[string][]DatabaseFilter `json:"filter,omitempty"`
+	Filter      map[string][]DatabaseFilter `json:"filter,omitempty"`
+	StartCursor string                      `json:"start_cursor,omitempty"`
+	PageSize    int                         `json:"page_size,omitempty"`
 }
 
 type DatabaseFilter struct {
-	Property string            `json:"property,omitempty"`
-	Select   map[string]string `json:"select,omitempty"`
-	Checkbox map[string]bool   `json:"checkbox,omitempty"`
-	URL      map[string]string `json:"url,omitempty"`
+	Property    string            `json:"property,omitempty"`
+	Select      map[string]string `json:"select,omitempty"`
+	MultiSelect map[string]string `json:"multi_select,omitempty"`
+	Checkbox    map[string]bool   `json:"checkbox,omitempty"`
+	URL         map[string]string `json:"url,omitempty"`
 }
 
 type DatabaseResponse struct {
@@ -33,14 +37,16 @@ type DatabaseItem struct {
 }
 
 type Property struct {
-	ID       string             `json:"id,omitempty"`
-	Type     string             `json:"type,omitempty"`
-	Title    []TitleProperty    `json:"title,omitempty"`
-	Checkbox bool               `json:"checkbox,omitempty"`
-	URL      string             `json:"url,omitempty"`
-	Select   *SelectProperty    `json:"select,omitempty"`
-	Date     *DateProperty      `json:"date,omitempty"`
-	RichText []RichTextProperty `json:"rich_text,omitempty"`
+	ID          string             `json:"id,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Title       []TitleProperty    `json:"title,omitempty"`
+	Checkbox    *bool              `json:"checkbox,omitempty"`
+	URL         string             `json:"url,omitempty"`
+	Select      *SelectProperty    `json:"select,omitempty"`
+	MultiSelect []SelectProperty   `json:"multi_select,omitempty"`
+	Date        *DateProperty      `json:"date,omitempty"`
+	RichText    []RichTextProperty `json:"rich_text,omitempty"`
+	Number      *float64           `json:"number,omitempty"`
 }
 
 type TitleProperty struct {
@@ -88,8 +94,12 @@ type Annotations struct {
 }
 
 type TextField struct {
-	Content string `json:"content"`
-	Link    string `json:"link,omitempty"`
+	Content string    `json:"content"`
+	Link    *TextLink `json:"link,omitempty"`
+}
+
+type TextLink struct {
+	URL string `json:"url"`
 }
 
 type Parent struct {
@@ -104,21 +114,115 @@ const (
 	OR  FilterCompoundType = "or"
 )
 
-func FetchDatabaseItems(databaseID string,
+// MaxPageSize is the largest page size accepted by Notion's paginated endpoints.
+const MaxPageSize = 100
+
+// FetchDatabaseItems returns every item matching the filters, following the cursor across pages.
+func (c *Client) FetchDatabaseItems(ctx context.Context,
+	databaseID string,
 	filters []DatabaseFilter,
 	compoundDesc FilterCompoundType) (dbItems []DatabaseItem, err error) {
-	url := fmt.Sprintf("https://api.notion.com/v1/databases/%s/query", databaseID)
-	reqBody := DatabaseRequestBody{
-		Filter: map[string][]DatabaseFilter{
+	err = c.QueryDatabase(ctx, databaseID, filters, compoundDesc, func(items []DatabaseItem) error {
+		dbItems = append(dbItems, items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dbItems, nil
+}
+
+// QueryDatabase queries the database page by page and hands every page to fn.
+// Iteration stops at the last page or as soon as fn returns an error.
+func (c *Client) QueryDatabase(ctx context.Context,
+	databaseID string,
+	filters []DatabaseFilter,
+	compoundDesc FilterCompoundType,
+	fn func(items []DatabaseItem) error) error {
+	path := fmt.Sprintf("/v1/databases/%s/query", databaseID)
+	reqBody := DatabaseRequestBody{PageSize: MaxPageSize}
+	if len(filters) > 0 {
+		reqBody.Filter = map[string][]DatabaseFilter{
 			string(compoundDesc): filters,
-		},
+		}
+	}
+
+	for {
+		database := &DatabaseResponse{}
+		err := c.makeRequest(ctx, http.MethodPost, path, reqBody, database)
+		if err != nil {
+			return err
+		}
+
+		err = fn(database.Results)
+		if err != nil {
+			return err
+		}
+
+		if !database.HasMore || database.NextCursor == "" {
+			return nil
+		}
+		reqBody.StartCursor = database.NextCursor
+	}
+}
+
+// Database is the schema of a Notion database as returned by the retrieve endpoint.
+type Database struct {
+	Object     string                      `json:"object"`
+	ID         string                      `json:"id"`
+	Title      []RichTextProperty          `json:"title,omitempty"`
+	Properties map[string]DatabaseProperty `json:"properties"`
+}
+
+type DatabaseProperty struct {
+	ID          string         `json:"id,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Type        string         `json:"type,omitempty"`
+	Select      *SelectOptions `json:"select,omitempty"`
+	MultiSelect *SelectOptions `json:"multi_select,omitempty"`
+}
+
+// SelectOptions are the options of a select or multi_select property.
+type SelectOptions struct {
+	Options []SelectProperty `json:"options"`
+}
+
+// Options returns the options of a select or multi_select property.
+func (p DatabaseProperty) Options() []SelectProperty {
+	switch {
+	case p.Select != nil:
+		return p.Select.Options
+	case p.MultiSelect != nil:
+		return p.MultiSelect.Options
+	default:
+		return nil
 	}
-	database := &DatabaseResponse{}
+}
+
+type DatabaseUpdateRequest struct {
+	Properties map[string]map[string]any `json:"properties"`
+}
+
+func (c *Client) RetrieveDatabase(ctx context.Context, databaseID string) (*Database, error) {
+	path := fmt.Sprintf("/v1/databases/%s", databaseID)
+	database := &Database{}
 
-	err = makeRequest(http.MethodPost, url, reqBody, database)
+	err := c.makeRequest(ctx, http.MethodGet, path, nil, database)
 	if err != nil {
 		return nil, err
 	}
+	return database, nil
+}
+
+// AddDatabaseProperties adds properties to the database, propertyTypes maps
+// each property name to its Notion type, e.g. "rich_text" or "date".
+func (c *Client) AddDatabaseProperties(ctx context.Context, databaseID string, propertyTypes map[string]string) error {
+	path := fmt.Sprintf("/v1/databases/%s", databaseID)
+	reqBody := DatabaseUpdateRequest{Properties: map[string]map[string]any{}}
+	for name, propertyType := range propertyTypes {
+		reqBody.Properties[name] = map[string]any{propertyType: map[string]any{}}
+	}
 
-	return database.Results, nil
+	return c.makeRequest(ctx, http.MethodPatch, path, reqBody, &Database{})
 }
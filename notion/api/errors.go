@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is a non-200 response from Notion with its error object decoded.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("notion api error, status:%d, code:%s, message:%s", e.StatusCode, e.Code, e.Message)
+}
+
+// Retryable reports whether the request may succeed when sent again:
+// rate limits, conflicts and server side failures are, bad requests are not.
+// A server side failure may still have been applied, see isRetryable.
+func (e *APIError) Retryable() bool {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests,
+		e.StatusCode == http.StatusConflict,
+		e.StatusCode >= http.StatusInternalServerError:
+		return true
+	default:
+		return false
+	}
+}
+
+type errorBody struct {
+	Object  string `json:"object"`
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	eb := errorBody{}
+	if json.Unmarshal(body, &eb) == nil && eb.Object == "error" {
+		apiErr.Code = eb.Code
+		apiErr.Message = eb.Message
+	} else {
+		apiErr.Message = string(body)
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// IsNotFound reports whether err is Notion's object_not_found error.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// isRetryable reports whether a failed request is sent again. A request that
+// may create something, like creating a page, is only retried when Notion
+// surely did not apply it, so a server error or a lost response does not
+// create a duplicate.
+func isRetryable(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if !idempotent {
+			return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusConflict
+		}
+		return apiErr.Retryable()
+	}
+	if !idempotent {
+		return false
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr)
+}
+
+// isIdempotent reports whether sending the request twice does the same as
+// sending it once. Every POST creates something except database queries, and
+// appending block children adds the blocks again.
+func isIdempotent(method, path string) bool {
+	endpoint := endpointOf(path)
+	switch method {
+	case http.MethodPost:
+		return strings.HasSuffix(endpoint, "/query")
+	case http.MethodPatch:
+		return endpoint != "/v1/blocks/:id/children"
+	default:
+		return true
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name         string
+		method, path string
+		err          error
+		want         bool
+	}{
+		{"get server error", http.MethodGet, "/v1/pages/abc", &APIError{StatusCode: http.StatusBadGateway}, true},
+		{"patch server error", http.MethodPatch, "/v1/pages/abc", &APIError{StatusCode: http.StatusInternalServerError}, true},
+		{"query server error", http.MethodPost, "/v1/databases/abc/query", &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"create page server error", http.MethodPost, "/v1/pages", &APIError{StatusCode: http.StatusBadGateway}, false},
+		{"create page rate limited", http.MethodPost, "/v1/pages", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"create page conflict", http.MethodPost, "/v1/pages", &APIError{StatusCode: http.StatusConflict}, true},
+		{"create page lost response", http.MethodPost, "/v1/pages", errors.New("connection reset"), false},
+		{"append children server error", http.MethodPatch, "/v1/blocks/abc/children", &APIError{StatusCode: http.StatusBadGateway}, false},
+		{"append children lost response", http.MethodPatch, "/v1/blocks/abc/children", errors.New("connection reset"), false},
+		{"append children rate limited", http.MethodPatch, "/v1/blocks/abc/children", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"get children server error", http.MethodGet, "/v1/blocks/abc/children", &APIError{StatusCode: http.StatusBadGateway}, true},
+		{"get lost response", http.MethodGet, "/v1/pages/abc", errors.New("connection reset"), true},
+		{"bad request", http.MethodGet, "/v1/pages/abc", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"cancelled", http.MethodGet, "/v1/pages/abc", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err, isIdempotent(tt.method, tt.path)); got != tt.want {
+				t.Errorf("isRetryable(%v) for %s %s = %v, want %v", tt.err, tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package api
+
+// Request size limits documented by Notion.
+const (
+	MaxRichTextLength     = 2000
+	MaxRichTextRuns       = 100
+	MaxChildrenPerRequest = 100
+	MaxBlocksPerRequest   = 1000
+)
+
+// SplitRichText cuts every run longer than MaxRichTextLength into several runs
+// with the same annotations and link.
+func SplitRichText(richText []RichTextProperty) []RichTextProperty {
+	var split []RichTextProperty
+	for _, rt := range richText {
+		for _, chunk := range splitText(rt.Text.Content, MaxRichTextLength) {
+			part := rt
+			part.Text.Content = chunk
+			part.PlainText = ""
+			split = append(split, part)
+		}
+	}
+	return split
+}
+
+// splitText splits s into chunks of at most limit UTF-16 code units, which is
+// how Notion counts characters.
+func splitText(s string, limit int) []string {
+	var chunks []string
+	start, units := 0, 0
+	for i, r := range s {
+		n := 1
+		if r >= 0x10000 {
+			n = 2
+		}
+		if units+n > limit {
+			chunks = append(chunks, s[start:i])
+			start, units = i, 0
+		}
+		units += n
+	}
+	return append(chunks, s[start:])
+}
+
+// SplitBlocks makes blocks fit Notion's size limits: long runs are split,
+// blocks with too many runs continue in extra paragraphs and children beyond
+// MaxChildrenPerRequest are moved after their parent.
+func SplitBlocks(blocks []Block) []Block {
+	var split []Block
+	for _, block := range blocks {
+		split = append(split, splitBlock(block)...)
+	}
+	return split
+}
+
+func splitBlock(block Block) []Block {
+	var extra []Block
+
+	richText := SplitRichText(block.RichText())
+	if len(richText) > MaxRichTextRuns {
+		for rest := richText[MaxRichTextRuns:]; len(rest) > 0; {
+			n := min(len(rest), MaxRichTextRuns)
+			extra = append(extra, ParagraphBlock(rest[:n]))
+			rest = rest[n:]
+		}
+		richText = richText[:MaxRichTextRuns]
+	}
+	block.setRichText(richText)
+
+	children := SplitBlocks(block.children())
+	if len(children) > MaxChildrenPerRequest {
+		extra = append(children[MaxChildrenPerRequest:], extra...)
+		children = children[:MaxChildrenPerRequest]
+	}
+	block.setChildren(children)
+
+	return append([]Block{block}, extra...)
+}
+
+// batchBlocks groups blocks into requests of at most MaxChildrenPerRequest top
+// level blocks and MaxBlocksPerRequest blocks including nested children.
+func batchBlocks(blocks []Block) [][]Block {
+	var batches [][]Block
+	var batch []Block
+	count := 0
+	for _, block := range blocks {
+		n := countBlocks(block)
+		if len(batch) > 0 && (len(batch) == MaxChildrenPerRequest || count+n > MaxBlocksPerRequest) {
+			batches = append(batches, batch)
+			batch, count = nil, 0
+		}
+		batch = append(batch, block)
+		count += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func countBlocks(block Block) int {
+	n := 1
+	for _, child := range block.children() {
+		n += countBlocks(child)
+	}
+	return n
+}
+
+func (b *Block) setRichText(richText []RichTextProperty) {
+	switch {
+	case b.Heading1 != nil:
+		b.Heading1.RichText = richText
+	case b.Heading2 != nil:
+		b.Heading2.RichText = richText
+	case b.Heading3 != nil:
+		b.Heading3.RichText = richText
+	case b.Paragraph != nil:
+		b.Paragraph.RichText = richText
+	case b.BulletedListItem != nil:
+		b.BulletedListItem.RichText = richText
+	case b.NumberedListItem != nil:
+		b.NumberedListItem.RichText = richText
+	case b.Quote != nil:
+		b.Quote.RichText = richText
+	case b.Code != nil:
+		b.Code.RichText = richText
+	case b.Callout != nil:
+		b.Callout.RichText = richText
+	case b.Toggle != nil:
+		b.Toggle.RichText = richText
+	}
+}
+
+func (b Block) children() []Block {
+	switch {
+	case b.Paragraph != nil:
+		return b.Paragraph.Children
+	case b.BulletedListItem != nil:
+		return b.BulletedListItem.Children
+	case b.NumberedListItem != nil:
+		return b.NumberedListItem.Children
+	case b.Quote != nil:
+		return b.Quote.Children
+	case b.Callout != nil:
+		return b.Callout.Children
+	case b.Toggle != nil:
+		return b.Toggle.Children
+	default:
+		return nil
+	}
+}
+
+func (b *Block) setChildren(children []Block) {
+	switch {
+	case b.Paragraph != nil:
+		b.Paragraph.Children = children
+	case b.BulletedListItem != nil:
+		b.BulletedListItem.Children = children
+	case b.NumberedListItem != nil:
+		b.NumberedListItem.Children = children
+	case b.Quote != nil:
+		b.Quote.Children = children
+	case b.Callout != nil:
+		b.Callout.Children = children
+	case b.Toggle != nil:
+		b.Toggle.Children = children
+	}
+}
+
+// splitProperties applies the rich text limits to title and rich_text properties.
+// Runs beyond MaxRichTextRuns are dropped, properties cannot continue elsewhere.
+func splitProperties(properties map[string]Property) map[string]Property {
+	split := make(map[string]Property, len(properties))
+	for name, prop := range properties {
+		if len(prop.RichText) > 0 {
+			prop.RichText = SplitRichText(prop.RichText)
+			if len(prop.RichText) > MaxRichTextRuns {
+				prop.RichText = prop.RichText[:MaxRichTextRuns]
+			}
+		}
+		if len(prop.Title) > 0 {
+			var title []TitleProperty
+			for _, t := range prop.Title {
+				for _, chunk := range splitText(t.Text.Content, MaxRichTextLength) {
+					part := t
+					part.Text.Content = chunk
+					part.PlainText = ""
+					title = append(title, part)
+				}
+			}
+			if len(title) > MaxRichTextRuns {
+				title = title[:MaxRichTextRuns]
+			}
+			prop.Title = title
+		}
+		split[name] = prop
+	}
+	return split
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+func TestSplitText(t *testing.T) {
+	emoji := "😀"
+
+	tests := []struct {
+		name string
+		text string
+		want []int // UTF-16 length of every chunk
+	}{
+		{name: "empty", text: "", want: []int{0}},
+		{name: "short", text: "hello", want: []int{5}},
+		{name: "exactly the limit", text: strings.Repeat("a", MaxRichTextLength), want: []int{2000}},
+		{name: "one over the limit", text: strings.Repeat("a", MaxRichTextLength+1), want: []int{2000, 1}},
+		{name: "cjk counts one unit", text: strings.Repeat("中", MaxRichTextLength+1), want: []int{2000, 1}},
+		{name: "surrogate pairs fill the limit", text: strings.Repeat(emoji, MaxRichTextLength/2), want: []int{2000}},
+		{name: "surrogate pair over the limit", text: strings.Repeat(emoji, MaxRichTextLength/2+1), want: []int{2000, 2}},
+		{name: "surrogate pair not cut at the limit", text: strings.Repeat("a", MaxRichTextLength-1) + emoji, want: []int{1999, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitText(tt.text, MaxRichTextLength)
+			if len(chunks) != len(tt.want) {
+				t.Fatalf("splitText() returned %d chunks, want %d", len(chunks), len(tt.want))
+			}
+			for i, chunk := range chunks {
+				if got := utf16Len(chunk); got != tt.want[i] {
+					t.Errorf("chunk %d has %d UTF-16 units, want %d", i, got, tt.want[i])
+				}
+			}
+			if got := strings.Join(chunks, ""); got != tt.text {
+				t.Errorf("chunks do not add up to the text")
+			}
+		})
+	}
+}
+
+func TestSplitRichText(t *testing.T) {
+	link := &TextLink{URL: "https://example.com"}
+	long := RichTextProperty{
+		Type:        "text",
+		Text:        TextField{Content: strings.Repeat("b", MaxRichTextLength*2+10), Link: link},
+		Annotations: Annotations{Bold: true},
+		PlainText:   "stale",
+	}
+	short := RichTextProperty{Type: "text", Text: TextField{Content: "tail"}}
+
+	split := SplitRichText([]RichTextProperty{long, short})
+	if len(split) != 4 {
+		t.Fatalf("SplitRichText() returned %d runs, want 4", len(split))
+	}
+	for i, rt := range split[:3] {
+		if !rt.Annotations.Bold || rt.Text.Link != link {
+			t.Errorf("run %d lost its annotations or link", i)
+		}
+		if rt.PlainText != "" {
+			t.Errorf("run %d keeps plain text %q", i, rt.PlainText)
+		}
+		if n := utf16Len(rt.Text.Content); n > MaxRichTextLength {
+			t.Errorf("run %d has %d UTF-16 units", i, n)
+		}
+	}
+	if split[3].Text.Content != "tail" {
+		t.Errorf("last run = %q, want tail", split[3].Text.Content)
+	}
+}
+
+func runs(n int) []RichTextProperty {
+	richText := make([]RichTextProperty, n)
+	for i := range richText {
+		richText[i] = RichTextProperty{Type: "text", Text: TextField{Content: "x"}}
+	}
+	return richText
+}
+
+func paragraphs(n int) []Block {
+	blocks := make([]Block, n)
+	for i := range blocks {
+		blocks[i] = ParagraphBlock(runs(1))
+	}
+	return blocks
+}
+
+func bulleted(richText []RichTextProperty, children []Block) Block {
+	return Block{
+		Object:           "block",
+		Type:             "bulleted_list_item",
+		BulletedListItem: &BlockListItem{RichText: richText, Children: children},
+	}
+}
+
+func TestSplitBlock(t *testing.T) {
+	tests := []struct {
+		name         string
+		block        Block
+		wantBlocks   int
+		wantRuns     int
+		wantChildren int
+	}{
+		{name: "within the limits", block: ParagraphBlock(runs(3)), wantBlocks: 1, wantRuns: 3},
+		{name: "long run split", block: ParagraphBlock([]RichTextProperty{{Text: TextField{Content: strings.Repeat("c", MaxRichTextLength+1)}}}), wantBlocks: 1, wantRuns: 2},
+		{name: "too many runs", block: ParagraphBlock(runs(MaxRichTextRuns*2 + 50)), wantBlocks: 3, wantRuns: MaxRichTextRuns},
+		{name: "too many children", block: bulleted(runs(1), paragraphs(MaxChildrenPerRequest+20)), wantBlocks: 21, wantRuns: 1, wantChildren: MaxChildrenPerRequest},
+		{name: "no rich text", block: Block{Object: "block", Type: "divider", Divider: &BlockDivider{}}, wantBlocks: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := splitBlock(tt.block)
+			if len(blocks) != tt.wantBlocks {
+				t.Fatalf("splitBlock() returned %d blocks, want %d", len(blocks), tt.wantBlocks)
+			}
+			first := blocks[0]
+			if got := len(first.RichText()); got != tt.wantRuns {
+				t.Errorf("first block has %d runs, want %d", got, tt.wantRuns)
+			}
+			if got := len(first.children()); got != tt.wantChildren {
+				t.Errorf("first block has %d children, want %d", got, tt.wantChildren)
+			}
+			for i, block := range blocks {
+				if n := len(block.RichText()); n > MaxRichTextRuns {
+					t.Errorf("block %d has %d runs", i, n)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchBlocks(t *testing.T) {
+	// Every block counts 1+99 blocks with its children, ten fill a request.
+	nested := make([]Block, 11)
+	for i := range nested {
+		nested[i] = bulleted(runs(1), paragraphs(99))
+	}
+
+	tests := []struct {
+		name   string
+		blocks []Block
+		want   []int // top level blocks of every batch
+	}{
+		{name: "none", blocks: nil, want: nil},
+		{name: "one request", blocks: paragraphs(MaxChildrenPerRequest), want: []int{100}},
+		{name: "top level limit", blocks: paragraphs(2*MaxChildrenPerRequest + 50), want: []int{100, 100, 50}},
+		{name: "nested block limit", blocks: nested, want: []int{10, 1}},
+		{name: "oversized block alone", blocks: append(paragraphs(1), bulleted(runs(1), paragraphs(MaxBlocksPerRequest))), want: []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := batchBlocks(tt.blocks)
+			if len(batches) != len(tt.want) {
+				t.Fatalf("batchBlocks() returned %d batches, want %d", len(batches), len(tt.want))
+			}
+			for i, batch := range batches {
+				if len(batch) != tt.want[i] {
+					t.Errorf("batch %d has %d blocks, want %d", i, len(batch), tt.want[i])
+				}
+			}
+		})
+	}
+}
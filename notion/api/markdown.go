@@ -0,0 +1,352 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// Notion accepts at most two levels of nested children in a single request,
+// deeper blocks are hoisted next to their parent.
+const maxNestingDepth = 2
+
+var calloutIcons = map[string]string{
+	"NOTE":      "ℹ️",
+	"TIP":       "💡",
+	"IMPORTANT": "❗",
+	"WARNING":   "⚠️",
+	"CAUTION":   "🛑",
+}
+
+var calloutMarker = regexp.MustCompile(`^\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*`)
+
+var detailsBlock = regexp.MustCompile(`(?is)<details[^>]*>\s*<summary[^>]*>(.*?)</summary>(.*?)</details>`)
+
+// codeLanguages maps common fenced code info strings to Notion code languages.
+var codeLanguages = map[string]string{
+	"bash": "bash", "sh": "shell", "shell": "shell", "zsh": "shell",
+	"c": "c", "cpp": "c++", "c++": "c++", "cs": "c#", "csharp": "c#",
+	"css": "css", "dart": "dart", "diff": "diff", "docker": "docker", "dockerfile": "docker",
+	"elixir": "elixir", "go": "go", "golang": "go", "graphql": "graphql",
+	"html": "html", "java": "java", "js": "javascript", "javascript": "javascript",
+	"json": "json", "kotlin": "kotlin", "kt": "kotlin", "lua": "lua",
+	"makefile": "makefile", "markdown": "markdown", "md": "markdown",
+	"php": "php", "protobuf": "protobuf", "proto": "protobuf", "py": "python", "python": "python",
+	"rb": "ruby", "ruby": "ruby", "rs": "rust", "rust": "rust", "scala": "scala",
+	"sql": "sql", "swift": "swift", "toml": "toml", "ts": "typescript", "typescript": "typescript",
+	"xml": "xml", "yaml": "yaml", "yml": "yaml",
+}
+
+// MarkdownToBlocks converts Markdown into Notion blocks, keeping headings, lists,
+// quotes, code, dividers and inline annotations. GitHub style alerts ("> [!NOTE]")
+// become callouts and <details><summary> HTML blocks become toggles.
+func MarkdownToBlocks(markdown string) []Block {
+	return markdownToBlocks(markdown, 0)
+}
+
+// markdownToBlocks cuts <details> sections out before parsing, since blackfriday
+// ends an HTML block at the first blank line inside it.
+func markdownToBlocks(markdown string, depth int) []Block {
+	var blocks []Block
+	for {
+		loc := detailsBlock.FindStringSubmatchIndex(markdown)
+		if loc == nil {
+			break
+		}
+
+		blocks = append(blocks, convertChildren(parseMarkdown(markdown[:loc[0]]), depth)...)
+		blocks = append(blocks, toggleBlock(markdown[loc[2]:loc[3]], markdown[loc[4]:loc[5]], depth)...)
+		markdown = markdown[loc[1]:]
+	}
+
+	return append(blocks, convertChildren(parseMarkdown(markdown), depth)...)
+}
+
+func parseMarkdown(markdown string) *blackfriday.Node {
+	md := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions))
+	return md.Parse([]byte(markdown))
+}
+
+func convertChildren(parent *blackfriday.Node, depth int) []Block {
+	var blocks []Block
+	for node := parent.FirstChild; node != nil; node = node.Next {
+		blocks = append(blocks, convertBlock(node, depth)...)
+	}
+	return blocks
+}
+
+func convertBlock(node *blackfriday.Node, depth int) []Block {
+	switch node.Type {
+	case blackfriday.Heading:
+		return []Block{headingBlock(node.HeadingData.Level, convertInline(node))}
+	case blackfriday.Paragraph:
+		richText := convertInline(node)
+		if len(richText) == 0 {
+			return nil
+		}
+		return []Block{ParagraphBlock(richText)}
+	case blackfriday.List:
+		return convertList(node, depth)
+	case blackfriday.BlockQuote:
+		return convertQuote(node, depth)
+	case blackfriday.CodeBlock:
+		return []Block{codeBlock(string(node.Literal), string(node.CodeBlockData.Info))}
+	case blackfriday.HorizontalRule:
+		return []Block{{Object: "block", Type: "divider", Divider: &BlockDivider{}}}
+	case blackfriday.HTMLBlock:
+		return convertHTMLBlock(string(node.Literal))
+	case blackfriday.Table:
+		return convertTable(node)
+	default:
+		return convertChildren(node, depth)
+	}
+}
+
+func headingBlock(level int, richText []RichTextProperty) Block {
+	heading := &BlockHeading{RichText: richText}
+	switch level {
+	case 1:
+		return Block{Object: "block", Type: "heading_1", Heading1: heading}
+	case 2:
+		return Block{Object: "block", Type: "heading_2", Heading2: heading}
+	default:
+		return Block{Object: "block", Type: "heading_3", Heading3: heading}
+	}
+}
+
+// ParagraphBlock wraps rich text into a paragraph block.
+func ParagraphBlock(richText []RichTextProperty) Block {
+	return Block{
+		Object:    "block",
+		Type:      "paragraph",
+		Paragraph: &BlockParagraph{RichText: richText},
+	}
+}
+
+func codeBlock(code, info string) Block {
+	language := "plain text"
+	if fields := strings.Fields(info); len(fields) > 0 {
+		if l, ok := codeLanguages[strings.ToLower(fields[0])]; ok {
+			language = l
+		}
+	}
+
+	return Block{
+		Object: "block",
+		Type:   "code",
+		Code: &BlockCode{
+			RichText: []RichTextProperty{textRun(strings.TrimRight(code, "\n"), Annotations{}, "")},
+			Language: language,
+		},
+	}
+}
+
+// splitContainer takes the text of the first paragraph of a container node as
+// the block's own rich text and converts everything else into its children.
+// Children nested too deep are returned separately to be hoisted by the caller.
+func splitContainer(node *blackfriday.Node, depth int) (richText []RichTextProperty, children, hoisted []Block) {
+	first := node.FirstChild
+	if first != nil && first.Type == blackfriday.Paragraph {
+		richText = convertInline(first)
+		first = first.Next
+	}
+
+	for child := first; child != nil; child = child.Next {
+		converted := convertBlock(child, depth+1)
+		if depth+1 >= maxNestingDepth {
+			hoisted = append(hoisted, converted...)
+		} else {
+			children = append(children, converted...)
+		}
+	}
+	return
+}
+
+func convertList(list *blackfriday.Node, depth int) []Block {
+	ordered := list.ListFlags&blackfriday.ListTypeOrdered != 0
+
+	var blocks []Block
+	for item := list.FirstChild; item != nil; item = item.Next {
+		richText, children, hoisted := splitContainer(item, depth)
+		listItem := &BlockListItem{RichText: richText, Children: children}
+
+		block := Block{Object: "block"}
+		if ordered {
+			block.Type = "numbered_list_item"
+			block.NumberedListItem = listItem
+		} else {
+			block.Type = "bulleted_list_item"
+			block.BulletedListItem = listItem
+		}
+		blocks = append(blocks, block)
+		blocks = append(blocks, hoisted...)
+	}
+	return blocks
+}
+
+func convertQuote(quote *blackfriday.Node, depth int) []Block {
+	richText, children, hoisted := splitContainer(quote, depth)
+
+	if len(richText) > 0 {
+		if m := calloutMarker.FindStringSubmatch(richText[0].Text.Content); m != nil {
+			richText[0].Text.Content = strings.TrimLeft(richText[0].Text.Content[len(m[0]):], "\n")
+			if richText[0].Text.Content == "" {
+				richText = richText[1:]
+			}
+			block := Block{
+				Object: "block",
+				Type:   "callout",
+				Callout: &BlockCallout{
+					RichText: richText,
+					Icon:     &Icon{Type: "emoji", Emoji: calloutIcons[m[1]]},
+					Color:    "gray_background",
+					Children: children,
+				},
+			}
+			return append([]Block{block}, hoisted...)
+		}
+	}
+
+	block := Block{
+		Object: "block",
+		Type:   "quote",
+		Quote:  &BlockQuote{RichText: richText, Children: children},
+	}
+	return append([]Block{block}, hoisted...)
+}
+
+func convertHTMLBlock(html string) []Block {
+	text := strings.TrimSpace(html)
+	if text == "" {
+		return nil
+	}
+	return []Block{ParagraphBlock([]RichTextProperty{textRun(text, Annotations{}, "")})}
+}
+
+func toggleBlock(summary, body string, depth int) []Block {
+	var children, hoisted []Block
+	if depth+1 >= maxNestingDepth {
+		hoisted = markdownToBlocks(body, depth)
+	} else {
+		children = markdownToBlocks(body, depth+1)
+	}
+
+	block := Block{
+		Object: "block",
+		Type:   "toggle",
+		Toggle: &BlockToggle{
+			RichText: []RichTextProperty{textRun(strings.TrimSpace(summary), Annotations{}, "")},
+			Children: children,
+		},
+	}
+	return append([]Block{block}, hoisted...)
+}
+
+// convertTable renders every table row as a paragraph with cells separated by " | ".
+func convertTable(table *blackfriday.Node) []Block {
+	var blocks []Block
+	table.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.TableRow {
+			return blackfriday.GoToNext
+		}
+
+		var richText []RichTextProperty
+		for cell := node.FirstChild; cell != nil; cell = cell.Next {
+			if cell != node.FirstChild {
+				richText = append(richText, textRun(" | ", Annotations{}, ""))
+			}
+			annotations := Annotations{Bold: cell.TableCellData.IsHeader}
+			richText = append(richText, inlineRuns(cell, annotations, "")...)
+		}
+		blocks = append(blocks, ParagraphBlock(mergeRuns(richText)))
+		return blackfriday.SkipChildren
+	})
+	return blocks
+}
+
+func convertInline(node *blackfriday.Node) []RichTextProperty {
+	return mergeRuns(inlineRuns(node, Annotations{}, ""))
+}
+
+func inlineRuns(parent *blackfriday.Node, annotations Annotations, link string) []RichTextProperty {
+	var runs []RichTextProperty
+	for node := parent.FirstChild; node != nil; node = node.Next {
+		switch node.Type {
+		case blackfriday.Text, blackfriday.HTMLSpan:
+			runs = append(runs, textRun(string(node.Literal), annotations, link))
+		case blackfriday.Code:
+			code := annotations
+			code.Code = true
+			runs = append(runs, textRun(string(node.Literal), code, link))
+		case blackfriday.Softbreak, blackfriday.Hardbreak:
+			runs = append(runs, textRun("\n", annotations, link))
+		case blackfriday.Emph:
+			emph := annotations
+			emph.Italic = true
+			runs = append(runs, inlineRuns(node, emph, link)...)
+		case blackfriday.Strong:
+			strong := annotations
+			strong.Bold = true
+			runs = append(runs, inlineRuns(node, strong, link)...)
+		case blackfriday.Del:
+			del := annotations
+			del.Strikethrough = true
+			runs = append(runs, inlineRuns(node, del, link)...)
+		case blackfriday.Link:
+			runs = append(runs, inlineRuns(node, annotations, string(node.LinkData.Destination))...)
+		case blackfriday.Image:
+			alt := inlineRuns(node, annotations, string(node.LinkData.Destination))
+			if len(alt) == 0 {
+				alt = []RichTextProperty{textRun(string(node.LinkData.Destination), annotations, string(node.LinkData.Destination))}
+			}
+			runs = append(runs, alt...)
+		default:
+			runs = append(runs, inlineRuns(node, annotations, link)...)
+		}
+	}
+	return runs
+}
+
+func textRun(content string, annotations Annotations, link string) RichTextProperty {
+	rt := RichTextProperty{
+		Type:        "text",
+		Text:        TextField{Content: content},
+		Annotations: annotations,
+	}
+	if link != "" {
+		rt.Text.Link = &TextLink{URL: link}
+	}
+	return rt
+}
+
+// mergeRuns joins adjacent runs sharing annotations and link, and drops empty ones.
+func mergeRuns(runs []RichTextProperty) []RichTextProperty {
+	var merged []RichTextProperty
+	for _, run := range runs {
+		if run.Text.Content == "" {
+			continue
+		}
+		if n := len(merged); n > 0 && sameStyle(merged[n-1], run) {
+			merged[n-1].Text.Content += run.Text.Content
+			continue
+		}
+		merged = append(merged, run)
+	}
+
+	if n := len(merged); n > 0 {
+		merged[0].Text.Content = strings.TrimLeft(merged[0].Text.Content, "\n")
+		merged[n-1].Text.Content = strings.TrimRight(merged[n-1].Text.Content, "\n")
+	}
+	return merged
+}
+
+func sameStyle(a, b RichTextProperty) bool {
+	if a.Annotations != b.Annotations {
+		return false
+	}
+	if a.Text.Link == nil || b.Text.Link == nil {
+		return a.Text.Link == nil && b.Text.Link == nil
+	}
+	return a.Text.Link.URL == b.Text.Link.URL
+}
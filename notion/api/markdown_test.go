@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// TestMarkdownToBlocks converts every testdata/*.md file and compares the
+// blocks with the matching *.golden.json file.
+func TestMarkdownToBlocks(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no markdown fixtures in testdata")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".md")
+		t.Run(name, func(t *testing.T) {
+			markdown, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := json.MarshalIndent(MarkdownToBlocks(string(markdown)), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(file, ".md") + ".golden.json"
+			if *update {
+				err = os.WriteFile(golden, got, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file, run with -update to create it: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("blocks of %s differ from %s:\n%s", file, golden, got)
+			}
+		})
+	}
+}
+
+func TestMarkdownToBlocksNesting(t *testing.T) {
+	blocks := MarkdownToBlocks("- one\n  - two\n    - three\n      - four\n")
+
+	depth := 0
+	for children := blocks; len(children) > 0; depth++ {
+		var next []Block
+		for _, block := range children {
+			next = append(next, block.children()...)
+		}
+		children = next
+	}
+	if depth > maxNestingDepth {
+		t.Errorf("blocks are nested %d levels deep, want at most %d", depth, maxNestingDepth)
+	}
+	total := 0
+	for _, block := range blocks {
+		total += countBlocks(block)
+	}
+	if total != 4 {
+		t.Errorf("got %d blocks, want all 4 list items kept", total)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -18,6 +19,7 @@ type PageCreateResponse struct {
 
 type PageUpdateRequest struct {
 	Properties map[string]Property `json:"properties,omitempty"`
+	Archived   bool                `json:"archived,omitempty"`
 }
 
 type PageUpdateResponse struct {
@@ -25,31 +27,54 @@ type PageUpdateResponse struct {
 	ID     string `json:"id,omitempty"`
 }
 
-func CreatePageInDatabase(databaseID string,
+// CreatePageInDatabase creates the page with as many children as one request
+// allows and appends the remaining children afterwards.
+func (c *Client) CreatePageInDatabase(ctx context.Context,
+	databaseID string,
 	properties map[string]Property,
 	children []Block) (string, error) {
-	url := "https://api.notion.com/v1/pages"
+	batches := batchBlocks(SplitBlocks(children))
 	reqBody := PageCreateRequest{
 		Parent:     Parent{DatabaseID: databaseID},
-		Properties: properties,
-		Children:   children,
+		Properties: splitProperties(properties),
+	}
+	if len(batches) > 0 {
+		reqBody.Children = batches[0]
 	}
 	page := &PageCreateResponse{}
 
-	err := makeRequest(http.MethodPost, url, reqBody, page)
+	err := c.makeRequest(ctx, http.MethodPost, "/v1/pages", reqBody, page)
 	if err != nil {
 		return "", err
 	}
 
+	for i := 1; i < len(batches); i++ {
+		err = c.appendBlockChildren(ctx, page.ID, batches[i])
+		if err != nil {
+			return page.ID, err
+		}
+	}
+
 	return page.ID, nil
 }
 
-func UpdatePage(pageID string, properties map[string]Property) error {
-	url := fmt.Sprintf("https://api.notion.com/v1/pages/%s", pageID)
+func (c *Client) UpdatePage(ctx context.Context, pageID string, properties map[string]Property) error {
+	path := fmt.Sprintf("/v1/pages/%s", pageID)
 	reqBody := PageUpdateRequest{
-		Properties: properties,
+		Properties: splitProperties(properties),
 	}
 	page := &PageUpdateResponse{}
 
-	return makeRequest(http.MethodPatch, url, reqBody, page)
+	return c.makeRequest(ctx, http.MethodPatch, path, reqBody, page)
+}
+
+// ArchivePage moves the page to the trash. A page that no longer exists
+// counts as archived.
+func (c *Client) ArchivePage(ctx context.Context, pageID string) error {
+	path := fmt.Sprintf("/v1/pages/%s", pageID)
+	err := c.makeRequest(ctx, http.MethodPatch, path, PageUpdateRequest{Archived: true}, &PageUpdateResponse{})
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
 }
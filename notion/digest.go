@@ -0,0 +1,66 @@
+package notion
+
+import (
+	"log"
+	"notion-summary/notification"
+	"notion-summary/store"
+	"sort"
+)
+
+// SendDigest emails every post saved since the last digest and clears their
+// pending flag once the email is sent.
+func SendDigest() error {
+	if !notification.DigestEnabled() {
+		return nil
+	}
+
+	var items []*store.Item
+	err := store.Default.ForEach(func(item *store.Item) error {
+		if item.DigestPending && item.Status == store.StatusSaved {
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		log.Println("Not any posts for the digest")
+		return nil
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].PublishTime.After(items[j].PublishTime)
+	})
+	posts := make([]notification.DigestPost, len(items))
+	for i, item := range items {
+		title := item.Title
+		if item.CNTitle != "" && item.CNTitle != item.Title {
+			title = item.CNTitle + "（" + item.Title + "）"
+		}
+		posts[i] = notification.DigestPost{
+			Link:        item.Link,
+			Title:       title,
+			BlogAddr:    item.BlogAddr,
+			Author:      item.Authors,
+			PublishTime: item.PublishTime,
+			Summary:     item.Outline,
+		}
+	}
+
+	log.Printf("Send digest with %d posts\n", len(posts))
+	err = notification.SendDigest(posts)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		err := store.Default.Update(item.Key, func(item *store.Item) {
+			item.DigestPending = false
+		})
+		if err != nil {
+			log.Printf("clear digest flag of %s error:%v\n", item.Link, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"notion-summary/config"
+	notionAPI "notion-summary/notion/api"
+)
+
+// Feed is a row of the RSS database.
+type Feed struct {
+	ID      string
+	Name    string
+	URL     string
+	Enabled bool
+}
+
+// ListFeeds returns every row of the RSS database, enabled or not.
+func ListFeeds(ctx context.Context, client *notionAPI.Client) ([]Feed, error) {
+	dbItems, err := client.FetchDatabaseItems(ctx, config.Notion.NotionRssDBID, nil, notionAPI.AND)
+	if err != nil {
+		return nil, err
+	}
+
+	rssProps := config.Properties.RSS
+	feeds := make([]Feed, 0, len(dbItems))
+	for _, item := range dbItems {
+		prop := item.Properties
+		feed := Feed{
+			ID:  item.ID,
+			URL: prop[rssProps.URL].URL,
+		}
+		feed.Name = notionAPI.PlainText(titleAsRichText(prop[rssProps.Name].Title))
+		if enabled := prop[rssProps.Enabled].Checkbox; enabled != nil {
+			feed.Enabled = *enabled
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, nil
+}
+
+// AddFeed creates an enabled subscription in the RSS database.
+func AddFeed(ctx context.Context, client *notionAPI.Client, name, url string) (string, error) {
+	rssProps := config.Properties.RSS
+	enabled := true
+	props := map[string]notionAPI.Property{
+		rssProps.Name: {
+			Title: []notionAPI.TitleProperty{
+				{Text: notionAPI.TextField{Content: name}},
+			},
+		},
+		rssProps.URL:     {URL: url},
+		rssProps.Enabled: {Checkbox: &enabled},
+	}
+
+	return client.CreatePageInDatabase(ctx, config.Notion.NotionRssDBID, props, nil)
+}
+
+// DisableFeed unchecks Enabled on the subscription whose id, name or url equals key.
+func DisableFeed(ctx context.Context, client *notionAPI.Client, key string) (*Feed, error) {
+	feeds, err := ListFeeds(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []Feed
+	for _, feed := range feeds {
+		if feed.ID == key || feed.Name == key || feed.URL == key {
+			matched = append(matched, feed)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("no subscription matches %q", key)
+	case 1:
+	default:
+		return nil, fmt.Errorf("%d subscriptions match %q, use the id instead", len(matched), key)
+	}
+
+	disabled := false
+	props := map[string]notionAPI.Property{
+		config.Properties.RSS.Enabled: {Checkbox: &disabled},
+	}
+	err = client.UpdatePage(ctx, matched[0].ID, props)
+	if err != nil {
+		return nil, err
+	}
+
+	matched[0].Enabled = false
+	return &matched[0], nil
+}
+
+func titleAsRichText(title []notionAPI.TitleProperty) []notionAPI.RichTextProperty {
+	richText := make([]notionAPI.RichTextProperty, len(title))
+	for i, t := range title {
+		richText[i] = notionAPI.RichTextProperty{Text: t.Text, PlainText: t.PlainText}
+	}
+	return richText
+}
@@ -0,0 +1,86 @@
+package notion
+
+import (
+	"context"
+	"log"
+	"notion-summary/config"
+	"notion-summary/notification"
+	"slices"
+)
+
+// NotifySubscriptions announces the posts saved by the current sync on the chat
+// channels of their subscriptions, sending one batch per channel. Posts scored
+// below the relevance threshold are not announced.
+func NotifySubscriptions(ctx context.Context, subscriptions []*Subscription) error {
+	if len(config.Notifiers) == 0 {
+		return nil
+	}
+
+	notifiers, err := notification.NewNotifiers(config.Notifiers)
+	if err != nil {
+		return err
+	}
+
+	batches := map[string][]notification.Notification{}
+	for _, s := range subscriptions {
+		if len(s.saved) == 0 {
+			continue
+		}
+
+		channels := s.channels()
+		for _, post := range s.saved {
+			if post.Summary != nil && post.Summary.BelowThreshold {
+				continue
+			}
+			n := post.notification(s)
+			for _, channel := range channels {
+				if _, exist := notifiers[channel]; !exist {
+					log.Printf("[%s] unknown notify channel:%s\n", s.Name, channel)
+					continue
+				}
+				batches[channel] = append(batches[channel], n)
+			}
+		}
+	}
+
+	for channel, posts := range batches {
+		log.Printf("Notify %d posts to %s\n", len(posts), channel)
+		err := notifiers[channel].Notify(ctx, posts)
+		if err != nil {
+			log.Printf("notify %s error:%v\n", channel, err)
+		}
+	}
+	return nil
+}
+
+// channels returns the channels picked in the RSS database, or else every
+// configured channel that is not limited to other subscriptions.
+func (s *Subscription) channels() []string {
+	if len(s.Channels) > 0 {
+		return s.Channels
+	}
+
+	var channels []string
+	for _, n := range config.Notifiers {
+		if len(n.Subscriptions) == 0 || slices.Contains(n.Subscriptions, s.Name) {
+			channels = append(channels, n.Name)
+		}
+	}
+	return channels
+}
+
+func (post *Post) notification(s *Subscription) notification.Notification {
+	var cnTitle, outline string
+	if post.Summary != nil {
+		cnTitle, outline = post.Summary.Title, post.Summary.Outline
+	}
+	return notification.Notification{
+		Subscription: s.Name,
+		Title:        post.Title,
+		CNTitle:      cnTitle,
+		Outline:      outline,
+		Link:         post.Link,
+		Authors:      post.Authors,
+		PublishTime:  post.PublishTime,
+	}
+}
@@ -1,42 +1,225 @@
 package notion
 
 import (
+	"context"
+	"errors"
 	"log"
 	"notion-summary/config"
+	"notion-summary/metrics"
+	notionAPI "notion-summary/notion/api"
+	"notion-summary/store"
+	"time"
 
 	"github.com/robfig/cron/v3"
 )
 
-func InitCronJobs() {
+// InitCronJobs schedules the jobs and returns the running scheduler. The jobs
+// run with ctx, cancelling it makes the runs in progress stop at the next post.
+func InitCronJobs(ctx context.Context) *cron.Cron {
 	c := cron.New(cron.WithSeconds())
 
-	DoSummaryJob(c)
+	DoSummaryJob(ctx, c)
+	DoDigestJob(c)
 
 	c.Start()
+	return c
 }
 
-func DoSummaryJob(c *cron.Cron) {
-	var summaryJob = func() {
-		log.Println("QueryPosts...")
-		subscriptions, err := QuerySubscriptions()
-		if err != nil {
-			log.Printf("QueryPosts error:%v\n", err)
-			return
+// NewClient builds a Notion client from the Notion section of config.
+func NewClient() *notionAPI.Client {
+	return notionAPI.NewClient(
+		notionAPI.WithBaseURL(config.Notion.BaseURL),
+		notionAPI.WithToken(config.Notion.NotionApiKey),
+		notionAPI.WithVersion(config.Notion.Version),
+		notionAPI.WithTimeout(time.Duration(config.Notion.TimeoutSeconds)*time.Second),
+		notionAPI.WithRateLimit(config.Notion.RequestsPerSecond),
+		notionAPI.WithRequestHook(metrics.ObserveNotionRequest),
+	)
+}
+
+// SyncOptions tunes a single sync run.
+type SyncOptions struct {
+	// Since ignores the watermarks and the per feed limits, picking every
+	// post published after it that is not in Notion yet.
+	Since time.Time
+	// Trigger tells what started the run, it is recorded with the run.
+	Trigger string
+}
+
+// RunSync performs a sync and records it as a run in the state store. It
+// returns ErrSyncRunning without waiting when another sync is in progress.
+func RunSync(ctx context.Context, client *notionAPI.Client, opts SyncOptions) (*store.Run, error) {
+	run, done, err := beginSync(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	err = runSync(ctx, client, opts, run)
+	return run, err
+}
+
+// StartSync is RunSync in the background, the returned run is the record as
+// it was when the run started.
+func StartSync(ctx context.Context, client *notionAPI.Client, opts SyncOptions) (*store.Run, error) {
+	run, done, err := beginSync(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	started := *run
+	go func() {
+		defer done()
+		runSync(ctx, client, opts, run)
+	}()
+	return &started, nil
+}
+
+// beginSync takes the sync lock and records the run, done releases both.
+func beginSync(opts SyncOptions) (run *store.Run, done func(), err error) {
+	err = beginRun()
+	if err != nil {
+		return nil, nil, err
+	}
+	unlock, err := lockSync()
+	if err != nil {
+		endRun()
+		return nil, nil, err
+	}
+
+	run, err = store.Default.StartRun(opts.Trigger)
+	if err != nil {
+		unlock()
+		endRun()
+		return nil, nil, err
+	}
+
+	return run, func() {
+		unlock()
+		endRun()
+	}, nil
+}
+
+// runSync fetches the subscriptions, summarizes their new posts, saves them to
+// Notion and sends the notifications.
+func runSync(ctx context.Context, client *notionAPI.Client, opts SyncOptions, run *store.Run) (err error) {
+	var subscriptions []*Subscription
+	defer func() {
+		run.Subscriptions = len(subscriptions)
+		run.Posts = runPosts(subscriptions)
+		if finishErr := store.Default.FinishRun(run, err); finishErr != nil {
+			log.Printf("record run %s error:%v\n", run.ID, finishErr)
+		}
+	}()
+
+	log.Println("QueryPosts...")
+	subscriptions, err = QuerySubscriptions(ctx, client, opts)
+	if err != nil {
+		log.Printf("QueryPosts error:%v\n", err)
+		return err
+	}
+	if len(subscriptions) == 0 {
+		metrics.LastSuccessfulSync.SetToCurrentTime()
+		return nil
+	}
+
+	LoadPromptPage(ctx, client)
+	ProcessSubscriptions(ctx, client, subscriptions)
+
+	notifyErr := NotifySubscriptions(ctx, subscriptions)
+	if notifyErr != nil {
+		log.Printf("NotifySubscriptions error:%v\n", notifyErr)
+	}
+
+	if config.Email.DigestInterval == "" {
+		digestErr := SendDigest()
+		if digestErr != nil {
+			log.Printf("SendDigest error:%v\n", digestErr)
 		}
-		if len(subscriptions) == 0 {
-			return
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	metrics.LastSuccessfulSync.SetToCurrentTime()
+
+	stats, statsErr := store.Default.Stats()
+	if statsErr != nil {
+		log.Printf("state store stats error:%v\n", statsErr)
+		return nil
+	}
+	log.Printf("Sync state: %d pending, %d summarized, %d saved, %d failed\n",
+		stats[store.StatusPending], stats[store.StatusSummarized],
+		stats[store.StatusSaved], stats[store.StatusFailed])
+	return nil
+}
+
+// runPosts reads the outcome of every post of the run from the state store.
+func runPosts(subscriptions []*Subscription) []store.RunPost {
+	posts := []store.RunPost{}
+	for _, s := range subscriptions {
+		for _, post := range s.Posts {
+			posts = append(posts, post.runPost(s.Name))
 		}
+	}
+	return posts
+}
 
-		log.Println("UpdateSubscriptionsInfos...")
-		err = UpdateSubscriptionsInfos(subscriptions)
-		if err != nil {
-			log.Printf("SaveBlogSummariesToNotion error:%v\n", err)
-			return
+func (post *Post) runPost(subscription string) store.RunPost {
+	result := store.RunPost{
+		Subscription: subscription,
+		Title:        post.Title,
+		Link:         post.Link,
+		Status:       store.StatusPending,
+	}
+	if post.stateKey == "" {
+		return result
+	}
+
+	item, err := store.Default.Get(post.stateKey)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = item.Status
+	result.NotionPageID = item.NotionPageID
+	result.Error = item.LastError
+	return result
+}
+
+// DoSummaryJob starts a first sync in the background and schedules the next
+// ones. A tick is skipped while the previous sync is still running.
+func DoSummaryJob(ctx context.Context, c *cron.Cron) {
+	client := NewClient()
+	var summaryJob = func() {
+		_, err := RunSync(ctx, client, SyncOptions{Trigger: "schedule"})
+		if errors.Is(err, ErrSyncRunning) {
+			log.Println("skip the summary job, a sync is already running")
 		}
 	}
-	summaryJob()
+	job := cron.NewChain(cron.SkipIfStillRunning(cron.VerbosePrintfLogger(log.Default()))).Then(cron.FuncJob(summaryJob))
+	go job.Run()
+
+	_, err := c.AddJob(config.Service.BlogSyncInterval, job)
+	if err != nil {
+		log.Printf("err:%v", err)
+		return
+	}
+}
+
+// DoDigestJob schedules the digest email on its own cron when an interval is configured,
+// otherwise the digest goes out at the end of every summary job.
+func DoDigestJob(c *cron.Cron) {
+	if config.Email.DigestInterval == "" {
+		return
+	}
 
-	_, err := c.AddFunc(config.Service.BlogSyncInterval, summaryJob)
+	_, err := c.AddFunc(config.Email.DigestInterval, func() {
+		err := SendDigest()
+		if err != nil {
+			log.Printf("SendDigest error:%v\n", err)
+		}
+	})
 	if err != nil {
 		log.Printf("err:%v", err)
 		return
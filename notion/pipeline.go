@@ -0,0 +1,207 @@
+package notion
+
+import (
+	"context"
+	"log"
+	"notion-summary/article"
+	"notion-summary/config"
+	"notion-summary/kimi"
+	"notion-summary/metrics"
+	notionAPI "notion-summary/notion/api"
+	"notion-summary/store"
+	"sync"
+)
+
+// postJob is a post on its way through the summarize and write stages.
+type postJob struct {
+	s    *Subscription
+	post *Post
+}
+
+// ProcessSubscriptions fetches, dedupes, summarizes and saves the new posts of
+// every subscription, then advances their watermarks. Every stage runs its own
+// bounded set of workers and hands over through a channel no larger than the
+// next stage's workers, so a slow stage holds back the ones before it. Once
+// ctx is done the stages only drain, the skipped posts stay in the state store
+// for the next sync.
+func ProcessSubscriptions(ctx context.Context, client *notionAPI.Client, subscriptions []*Subscription) {
+	conf := config.Pipeline
+	summarizer := kimi.DefaultSummarizer()
+
+	feeds := make(chan *Subscription)
+	fetched := make(chan *Subscription, conf.DedupeWorkers)
+	deduped := make(chan postJob, conf.SummarizeWorkers)
+	summarized := make(chan postJob, conf.WriteWorkers)
+
+	go func() {
+		for _, s := range subscriptions {
+			feeds <- s
+		}
+		close(feeds)
+	}()
+
+	log.Println("Begin to fetch posts according to your subscriptions...")
+	runStage(conf.FetchWorkers, fetched, func() {
+		for s := range feeds {
+			if release, err := article.AcquireHost(ctx, s.URL); err == nil {
+				s.fetchRSSPosts(ctx)
+				release()
+			}
+			fetched <- s
+		}
+	})
+
+	runStage(conf.DedupeWorkers, deduped, func() {
+		for s := range fetched {
+			if ctx.Err() != nil || len(s.Posts) == 0 {
+				continue
+			}
+			s.Posts = s.dedupePosts(ctx, client)
+			for _, post := range s.Posts {
+				deduped <- postJob{s: s, post: post}
+			}
+		}
+	})
+
+	runStage(conf.SummarizeWorkers, summarized, func() {
+		for job := range deduped {
+			if ctx.Err() != nil {
+				continue
+			}
+			if job.post.summarizeOnce(ctx, summarizer) {
+				summarized <- job
+			}
+		}
+	})
+
+	var writers sync.WaitGroup
+	writers.Add(conf.WriteWorkers)
+	for i := 0; i < conf.WriteWorkers; i++ {
+		go func() {
+			defer writers.Done()
+			for job := range summarized {
+				if ctx.Err() != nil {
+					continue
+				}
+				job.s.savePost(ctx, client, job.post)
+			}
+		}()
+	}
+	writers.Wait()
+
+	for _, s := range subscriptions {
+		s.finish(ctx, client)
+	}
+}
+
+// runStage starts workers running work and closes out once all of them return.
+func runStage[T any](workers int, out chan T, work func()) {
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			work()
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+}
+
+// summarizeOnce summarizes the post unless the state store kept its summary,
+// it reports whether the post has a summary to save.
+func (post *Post) summarizeOnce(ctx context.Context, summarizer kimi.Summarizer) bool {
+	if post.Summary != nil {
+		log.Printf("reuse stored summary, %s: \"%s\" \n", post.Authors, post.Title)
+		return true
+	}
+
+	log.Printf("summarize post, %s: \"%s\" \n", post.Authors, post.Title)
+	err := post.summarize(ctx, summarizer)
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		log.Printf("summarize post %s error:%v\n", post.Title, err)
+		metrics.PostFailures.WithLabelValues(metrics.StageSummarized).Inc()
+		post.recordState(func(item *store.Item) {
+			item.Status = store.StatusFailed
+			item.Attempts++
+			item.LastError = err.Error()
+		})
+		return false
+	}
+
+	metrics.Posts.WithLabelValues(metrics.StageSummarized).Inc()
+	post.recordState(func(item *store.Item) {
+		item.Status = store.StatusSummarized
+		item.Summary = post.Summary.JSON()
+		item.LastError = ""
+	})
+	return true
+}
+
+// savePost writes the summarized post to the Post database and records the outcome.
+func (s *Subscription) savePost(ctx context.Context, client *notionAPI.Client, post *Post) {
+	postDBID := config.Notion.NotionPostDBID
+	log.Printf("[%s] save summary to notion, title:%s\n", postDBID, post.Title)
+	pageID, err := post.saveSummaryToNotion(ctx, client, postDBID)
+	if ctx.Err() != nil && pageID == "" {
+		return
+	}
+	if err != nil {
+		log.Printf("saveSummaryToNotion error, Name:%s, err:%v\n", post.Title, err)
+		metrics.PostFailures.WithLabelValues(metrics.StageSaved).Inc()
+		post.recordState(func(item *store.Item) {
+			item.Status = store.StatusFailed
+			item.Attempts++
+			item.LastError = err.Error()
+			if pageID != "" {
+				item.NotionPageID = pageID
+			}
+		})
+		return
+	}
+
+	post.recordState(func(item *store.Item) {
+		item.Status = store.StatusSaved
+		item.NotionPageID = pageID
+		item.LastError = ""
+		item.Authors = post.Authors
+		item.BlogAddr = post.BlogAddr
+		item.PublishTime = post.PublishTime
+		item.CNTitle = post.Summary.Title
+		item.Outline = post.Summary.Outline
+		// Posts scored below the relevance threshold stay out of the digest.
+		item.DigestPending = !post.Summary.BelowThreshold
+	})
+	metrics.Posts.WithLabelValues(metrics.StageSaved).Inc()
+	post.saved = true
+}
+
+// finish collects the posts saved by this sync and advances the watermark.
+// Posts that were not saved, failed or skipped, hold the watermark back.
+func (s *Subscription) finish(ctx context.Context, client *notionAPI.Client) {
+	if len(s.Posts) == 0 {
+		log.Printf("[%s] not any new posts", s.Name)
+		return
+	}
+
+	var saved, failed []*Post
+	for _, post := range s.Posts {
+		if post.saved {
+			saved = append(saved, post)
+		} else {
+			failed = append(failed, post)
+		}
+	}
+
+	s.saved = saved
+	err := s.advanceWatermark(ctx, client, saved, failed)
+	if err != nil {
+		log.Printf("[%s] update watermark error:%v\n", s.Name, err)
+	}
+}
@@ -0,0 +1,50 @@
+package notion
+
+import (
+	"context"
+	"log"
+	"notion-summary/config"
+	"notion-summary/kimi"
+	notionAPI "notion-summary/notion/api"
+	"strings"
+)
+
+// LoadPromptPage reads the prompt templates of the configured Notion page into
+// the default summarizer. Every heading names a template and the code block
+// below it is its text. The templates stay as they were when the page cannot
+// be read.
+func LoadPromptPage(ctx context.Context, client *notionAPI.Client) {
+	prompts := kimi.DefaultPrompts()
+	if config.Notion.PromptPageID == "" || prompts == nil {
+		return
+	}
+
+	blocks, err := client.FetchBlockChilds(ctx, config.Notion.PromptPageID)
+	if err != nil {
+		log.Printf("fetch prompt page error:%v\n", err)
+		return
+	}
+
+	texts := promptTemplates(blocks)
+	err = prompts.Override(texts)
+	if err != nil {
+		log.Printf("load prompt page error:%v\n", err)
+		return
+	}
+	log.Printf("Loaded %d prompt templates from notion\n", len(texts))
+}
+
+func promptTemplates(blocks []notionAPI.Block) map[string]string {
+	texts := map[string]string{}
+	name := ""
+	for _, block := range blocks {
+		switch {
+		case block.Heading1 != nil || block.Heading2 != nil || block.Heading3 != nil:
+			name = strings.TrimSpace(notionAPI.PlainText(block.RichText()))
+		case block.Code != nil && name != "":
+			texts[name] = notionAPI.PlainText(block.Code.RichText)
+			name = ""
+		}
+	}
+	return texts
+}
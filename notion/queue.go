@@ -0,0 +1,189 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"log"
+	"notion-summary/config"
+	"notion-summary/kimi"
+	"notion-summary/metrics"
+	notionAPI "notion-summary/notion/api"
+	"notion-summary/store"
+	"time"
+)
+
+// queueSize is how many summarize requests may wait for the worker.
+const queueSize = 100
+
+var (
+	ErrQueueFull    = errors.New("summarize queue is full")
+	ErrQueueStopped = errors.New("summarize queue is not running")
+)
+
+type summarizeRequest struct {
+	link  string
+	style kimi.Style
+	run   *store.Run
+}
+
+var summarizeQueue chan summarizeRequest
+
+// StartSummarizeQueue starts the worker that summarizes the queued links one
+// after another and saves them to the post database. It stops with ctx.
+func StartSummarizeQueue(ctx context.Context, client *notionAPI.Client) {
+	summarizeQueue = make(chan summarizeRequest, queueSize)
+	go summarizeWorker(ctx, client, summarizeQueue)
+}
+
+// QueueSummarize records a run for link and queues it, the returned run is the
+// record as it was when queued. An empty style means the default one.
+func QueueSummarize(link string, style kimi.Style) (*store.Run, error) {
+	if summarizeQueue == nil {
+		return nil, ErrQueueStopped
+	}
+	if len(summarizeQueue) == cap(summarizeQueue) {
+		return nil, ErrQueueFull
+	}
+
+	runsMu.Lock()
+	stopping := draining
+	runsMu.Unlock()
+	if stopping {
+		return nil, ErrShuttingDown
+	}
+
+	run, err := store.Default.StartRun("summarize")
+	if err != nil {
+		return nil, err
+	}
+
+	queued := *run
+	select {
+	case summarizeQueue <- summarizeRequest{link: link, style: style, run: run}:
+		return &queued, nil
+	default:
+		store.Default.FinishRun(run, ErrQueueFull)
+		return nil, ErrQueueFull
+	}
+}
+
+func summarizeWorker(ctx context.Context, client *notionAPI.Client, queue chan summarizeRequest) {
+	for {
+		select {
+		case <-ctx.Done():
+			finishQueued(queue)
+			return
+		case req := <-queue:
+			err := beginRun()
+			if err != nil {
+				store.Default.FinishRun(req.run, err)
+				continue
+			}
+
+			err = summarizeLink(ctx, client, req.link, req.style, req.run)
+			if err != nil {
+				log.Printf("summarize %s error:%v\n", req.link, err)
+			}
+			endRun()
+		}
+	}
+}
+
+// finishQueued records the requests still waiting in queue as failed, so
+// their runs do not stay running after the worker stops.
+func finishQueued(queue chan summarizeRequest) {
+	for {
+		select {
+		case req := <-queue:
+			if err := store.Default.FinishRun(req.run, ErrShuttingDown); err != nil {
+				log.Printf("record run %s error:%v\n", req.run.ID, err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// summarizeLink summarizes a single link outside of any subscription. A link
+// already saved to Notion is not summarized again.
+func summarizeLink(ctx context.Context, client *notionAPI.Client, link string, style kimi.Style, run *store.Run) (err error) {
+	post := &Post{
+		Title:       link,
+		Link:        link,
+		PublishTime: time.Now(),
+		Style:       style,
+		vocabulary:  tagVocabulary(ctx, client),
+		profile:     interestProfile(ctx, client),
+		stateKey:    store.CanonicalLink(link),
+	}
+	defer func() {
+		run.Posts = []store.RunPost{post.runPost("")}
+		if finishErr := store.Default.FinishRun(run, err); finishErr != nil {
+			log.Printf("record run %s error:%v\n", run.ID, finishErr)
+		}
+	}()
+
+	item, err := store.Default.Get(post.stateKey)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		err = store.Default.Put(&store.Item{
+			Key:         post.stateKey,
+			Link:        link,
+			Title:       link,
+			PublishTime: post.PublishTime,
+			Status:      store.StatusPending,
+		})
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case item.Status == store.StatusSaved:
+		log.Printf("%s is already saved to notion\n", link)
+		return nil
+	default:
+		post.partialPageID = item.NotionPageID
+	}
+
+	err = post.summarize(ctx, kimi.DefaultSummarizer())
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		metrics.PostFailures.WithLabelValues(metrics.StageSummarized).Inc()
+		post.recordState(func(item *store.Item) {
+			item.Status = store.StatusFailed
+			item.Attempts++
+			item.LastError = err.Error()
+		})
+		return err
+	}
+	metrics.Posts.WithLabelValues(metrics.StageSummarized).Inc()
+
+	pageID, err := post.saveSummaryToNotion(ctx, client, config.Notion.NotionPostDBID)
+	if err != nil {
+		metrics.PostFailures.WithLabelValues(metrics.StageSaved).Inc()
+		post.recordState(func(item *store.Item) {
+			item.Status = store.StatusFailed
+			item.Summary = post.Summary.JSON()
+			item.Attempts++
+			item.LastError = err.Error()
+			if pageID != "" {
+				item.NotionPageID = pageID
+			}
+		})
+		return err
+	}
+
+	metrics.Posts.WithLabelValues(metrics.StageSaved).Inc()
+	post.recordState(func(item *store.Item) {
+		item.Status = store.StatusSaved
+		item.Summary = post.Summary.JSON()
+		item.NotionPageID = pageID
+		item.LastError = ""
+		item.CNTitle = post.Summary.Title
+		item.Outline = post.Summary.Outline
+		item.DigestPending = !post.Summary.BelowThreshold
+	})
+	return nil
+}
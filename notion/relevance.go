@@ -0,0 +1,37 @@
+package notion
+
+import (
+	"context"
+	"log"
+	"notion-summary/config"
+	"notion-summary/kimi"
+	notionAPI "notion-summary/notion/api"
+	"strings"
+)
+
+// interestProfile returns the interest profile of the config followed by the
+// text of the profile page. The config alone is used when the page cannot be
+// read.
+func interestProfile(ctx context.Context, client *notionAPI.Client) string {
+	profile := kimi.ConfigProfile(config.Relevance)
+	if config.Relevance.ProfilePageID == "" {
+		return profile
+	}
+
+	blocks, err := client.FetchBlockChilds(ctx, config.Relevance.ProfilePageID)
+	if err != nil {
+		log.Printf("fetch interest profile page error:%v\n", err)
+		return profile
+	}
+
+	var b strings.Builder
+	b.WriteString(profile)
+	for _, block := range blocks {
+		text := strings.TrimSpace(notionAPI.PlainText(block.RichText()))
+		if text != "" {
+			b.WriteString(text)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"notion-summary/config"
+	"notion-summary/store"
+	"sync"
+)
+
+var (
+	ErrSyncRunning  = errors.New("a sync is already running")
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+var (
+	// syncMu keeps syncs in this process from overlapping, the lock file
+	// does the same across processes.
+	syncMu sync.Mutex
+
+	runsMu   sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+)
+
+// lockSync takes the sync lock without waiting, it returns ErrSyncRunning when
+// another sync holds it here or in another process.
+func lockSync() (unlock func(), err error) {
+	if !syncMu.TryLock() {
+		return nil, ErrSyncRunning
+	}
+
+	fileLock, err := store.TryLockFile(config.Store.LockPath)
+	if err != nil {
+		syncMu.Unlock()
+		if errors.Is(err, store.ErrLocked) {
+			return nil, ErrSyncRunning
+		}
+		return nil, err
+	}
+
+	return func() {
+		fileLock.Unlock()
+		syncMu.Unlock()
+	}, nil
+}
+
+// beginRun registers a run with Drain, it fails once Drain has been called.
+func beginRun() error {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+
+	if draining {
+		return ErrShuttingDown
+	}
+	inflight.Add(1)
+	return nil
+}
+
+func endRun() {
+	inflight.Done()
+}
+
+// Drain refuses new syncs and summaries, then waits until the ones in progress
+// are done or ctx is done.
+func Drain(ctx context.Context) error {
+	runsMu.Lock()
+	draining = true
+	runsMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,158 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"notion-summary/config"
+	notionAPI "notion-summary/notion/api"
+	"strings"
+)
+
+// PropertyRequirement is a property the sync job reads or writes.
+// Optional properties may be missing but must have the right type when present.
+type PropertyRequirement struct {
+	Name     string
+	Type     string
+	Optional bool
+}
+
+// SchemaProblem describes one property that does not match the requirement.
+// Actual is empty when the property is missing.
+type SchemaProblem struct {
+	Database string
+	Property string
+	Expected string
+	Actual   string
+}
+
+func (p SchemaProblem) String() string {
+	if p.Actual == "" {
+		return fmt.Sprintf("%s database: property %q is missing, expected type %s", p.Database, p.Property, p.Expected)
+	}
+	return fmt.Sprintf("%s database: property %q has type %s, expected %s", p.Database, p.Property, p.Actual, p.Expected)
+}
+
+func rssRequirements() []PropertyRequirement {
+	props := config.Properties.RSS
+	return []PropertyRequirement{
+		{Name: props.Name, Type: "title"},
+		{Name: props.URL, Type: "url"},
+		{Name: props.Enabled, Type: "checkbox"},
+		{Name: props.MaxItems, Type: "number", Optional: true},
+		{Name: props.LastPublished, Type: "date", Optional: true},
+		{Name: props.LastGUID, Type: "rich_text", Optional: true},
+		{Name: props.Notify, Type: "multi_select", Optional: true},
+		{Name: props.Prompt, Type: "select", Optional: true},
+		{Name: props.Language, Type: "select", Optional: true},
+		{Name: props.Style, Type: "select", Optional: true},
+	}
+}
+
+func postRequirements() []PropertyRequirement {
+	props := config.Properties.Post
+	requirements := []PropertyRequirement{
+		{Name: props.Name, Type: "title"},
+		{Name: props.Authors, Type: "rich_text"},
+		{Name: props.CNTitle, Type: "rich_text"},
+		{Name: props.Published, Type: "date"},
+		{Name: props.Link, Type: "url"},
+		{Name: props.Outline, Type: "rich_text"},
+	}
+	if props.Tags != "" {
+		requirements = append(requirements, PropertyRequirement{Name: props.Tags, Type: "multi_select"})
+	}
+	if props.Score != "" {
+		requirements = append(requirements, PropertyRequirement{Name: props.Score, Type: "number"})
+	}
+	if props.Style != "" {
+		requirements = append(requirements, PropertyRequirement{Name: props.Style, Type: "select"})
+	}
+	return requirements
+}
+
+// ValidateSchemas checks the RSS and Post databases against the properties the
+// sync job needs. With fix set, missing required properties are added to the
+// database; properties with a wrong type are only reported.
+func ValidateSchemas(ctx context.Context, client *notionAPI.Client, fix bool) ([]SchemaProblem, error) {
+	databases := []struct {
+		name         string
+		id           string
+		requirements []PropertyRequirement
+	}{
+		{"RSS", config.Notion.NotionRssDBID, rssRequirements()},
+		{"Post", config.Notion.NotionPostDBID, postRequirements()},
+	}
+
+	var problems []SchemaProblem
+	for _, db := range databases {
+		dbProblems, err := validateDatabase(ctx, client, db.name, db.id, db.requirements, fix)
+		if err != nil {
+			return nil, fmt.Errorf("validate %s database: %w", db.name, err)
+		}
+		problems = append(problems, dbProblems...)
+	}
+	return problems, nil
+}
+
+func validateDatabase(ctx context.Context,
+	client *notionAPI.Client,
+	name, databaseID string,
+	requirements []PropertyRequirement,
+	fix bool) ([]SchemaProblem, error) {
+	database, err := client.RetrieveDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []SchemaProblem
+	missing := map[string]string{}
+	for _, req := range requirements {
+		if req.Name == "" {
+			continue
+		}
+		prop, exist := database.Properties[req.Name]
+		switch {
+		case !exist && req.Optional:
+			continue
+		case !exist:
+			missing[req.Name] = req.Type
+			problems = append(problems, SchemaProblem{Database: name, Property: req.Name, Expected: req.Type})
+		case prop.Type != req.Type:
+			problems = append(problems, SchemaProblem{Database: name, Property: req.Name, Expected: req.Type, Actual: prop.Type})
+		}
+	}
+
+	if !fix || len(missing) == 0 {
+		return problems, nil
+	}
+
+	// A database has exactly one title property, it can only be renamed, not added.
+	for propName, propType := range missing {
+		if propType == "title" {
+			delete(missing, propName)
+		}
+	}
+	if len(missing) == 0 {
+		return problems, nil
+	}
+
+	names := make([]string, 0, len(missing))
+	for propName := range missing {
+		names = append(names, propName)
+	}
+	log.Printf("Add missing properties to %s database: %s\n", name, strings.Join(names, ", "))
+	err = client.AddDatabaseProperties(ctx, databaseID, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	var remaining []SchemaProblem
+	for _, p := range problems {
+		if _, added := missing[p.Property]; added && p.Actual == "" {
+			continue
+		}
+		remaining = append(remaining, p)
+	}
+	return remaining, nil
+}
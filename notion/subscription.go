@@ -1,45 +1,83 @@
 package notion
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"notion-summary/article"
 	"notion-summary/config"
 	"notion-summary/kimi"
+	"notion-summary/metrics"
 	notionAPI "notion-summary/notion/api"
+	"notion-summary/store"
+	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/mmcdole/gofeed"
-	"github.com/russross/blackfriday/v2"
 )
 
 type Subscription struct {
-	ID    string
-	Name  string
-	URL   string
-	Posts []*Post
+	ID       string
+	Name     string
+	URL      string
+	MaxItems int
+	Posts    []*Post
+
+	// Channels picked in the RSS database, empty means the configured defaults.
+	Channels []string
+	// Prompt template, summary language and style picked in the RSS database, empty means the defaults.
+	Prompt   string
+	Language string
+	Style    kimi.Style
+	// Posts saved to Notion by the current sync.
+	saved []*Post
+	// Backfill start, set when the watermark is bypassed.
+	since time.Time
+	// Tags the summaries pick from, empty means any tags.
+	vocabulary []string
+	// Interest profile the posts are scored against, empty means no scoring.
+	profile string
+
+	// Watermark of the newest post handled by the previous sync.
+	LastPublished time.Time
+	LastGUID      string
+	hasWatermark  bool
 }
 
 type Post struct {
 	ID          string
 	Title       string
 	Authors     string
+	BlogAddr    string
 	Link        string
 	PublishTime time.Time
 	Content     string
-	Summary     []notionAPI.Block
+	Summary     *Summary
+	// Feed is the name of the subscription, Prompt, Language and Style are the ones it picked.
+	Feed     string
+	Prompt   string
+	Language string
+	Style    kimi.Style
+
+	vocabulary []string
+	profile    string
+	stateKey   string
+	// partialPageID is the page a failed save left half written, it is
+	// archived before the page is created again.
+	partialPageID string
+	// saved is set once the post is saved to Notion by the current sync.
+	saved bool
 }
 
-type Summary struct {
-	Title   string
-	Outline string
-	Content string
-}
+// Summary is the structured summary of a post, it fills the Notion page and the notifications.
+type Summary = kimi.Summary
 
-func QuerySubscriptions() ([]*Subscription, error) {
-	subscriptions, err := querySubscriptionsInNotion()
+// QuerySubscriptions reads the enabled subscriptions from the RSS database.
+func QuerySubscriptions(ctx context.Context, client *notionAPI.Client, opts SyncOptions) ([]*Subscription, error) {
+	subscriptions, err := querySubscriptionsInNotion(ctx, client)
 	if err != nil {
 		log.Printf("querySubscriptionsInNotion error:%v\n", err)
 		return nil, err
@@ -50,41 +88,22 @@ func QuerySubscriptions() ([]*Subscription, error) {
 		return nil, nil
 	}
 
-	log.Println("Begin to fetch posts according to your subscriptions...")
-	err = fetchPosts(subscriptions)
-	if err != nil {
-		return nil, err
+	vocabulary := tagVocabulary(ctx, client)
+	profile := interestProfile(ctx, client)
+	for _, s := range subscriptions {
+		s.since = opts.Since
+		s.vocabulary = vocabulary
+		s.profile = profile
 	}
-
-	makeSummarize(subscriptions)
-
 	return subscriptions, nil
 }
 
-// UpdateSubscriptionsInfos 将包含总结的信息写入notion中
-func UpdateSubscriptionsInfos(subscriptions []*Subscription) error {
-	var wg sync.WaitGroup
-	wg.Add(len(subscriptions))
-
-	for _, subscription := range subscriptions {
-		go func(s *Subscription) {
-			defer wg.Done()
-
-			s.savePostSummaryToNotion()
-		}(subscription)
-	}
-
-	wg.Wait()
-
-	return nil
-}
-
-func querySubscriptionsInNotion() ([]*Subscription, error) {
+func querySubscriptionsInNotion(ctx context.Context, client *notionAPI.Client) ([]*Subscription, error) {
 	log.Printf("query db:%s", config.Notion.NotionRssDBID)
-	dbItems, err := notionAPI.FetchDatabaseItems(config.Notion.NotionRssDBID,
+	dbItems, err := client.FetchDatabaseItems(ctx, config.Notion.NotionRssDBID,
 		[]notionAPI.DatabaseFilter{
 			{
-				Property: "Enabled",
+				Property: config.Properties.RSS.Enabled,
 				Checkbox: map[string]bool{"equals": true},
 			},
 		}, notionAPI.AND)
@@ -98,14 +117,40 @@ func querySubscriptionsInNotion() ([]*Subscription, error) {
 	}
 
 	var subscriptions []*Subscription
+	rssProps := config.Properties.RSS
 	log.Println("Your subscription list:")
 	for i, item := range dbItems {
 		prop := item.Properties
+		if len(prop[rssProps.Name].Title) == 0 || prop[rssProps.URL].URL == "" {
+			log.Printf("skip subscription %s without name or url\n", item.ID)
+			continue
+		}
 		s := Subscription{
-			ID:   item.ID,
-			Name: prop["Name"].Title[0].PlainText,
-			URL:  prop["URL"].URL,
+			ID:       item.ID,
+			Name:     prop[rssProps.Name].Title[0].PlainText,
+			URL:      prop[rssProps.URL].URL,
+			MaxItems: config.Service.MaxItemsPerFeed,
 		}
+		if maxItems := prop[rssProps.MaxItems].Number; maxItems != nil && *maxItems > 0 {
+			s.MaxItems = int(*maxItems)
+		}
+		for _, channel := range prop[rssProps.Notify].MultiSelect {
+			s.Channels = append(s.Channels, channel.Name)
+		}
+		if prompt := prop[rssProps.Prompt].Select; prompt != nil {
+			s.Prompt = prompt.Name
+		}
+		if language := prop[rssProps.Language].Select; language != nil {
+			s.Language = language.Name
+		}
+		if selected := prop[rssProps.Style].Select; selected != nil {
+			style, err := kimi.ParseStyle(selected.Name)
+			if err != nil {
+				log.Printf("[%s] %v, use the default style\n", s.Name, err)
+			}
+			s.Style = style
+		}
+		s.readWatermark(prop)
 
 		log.Printf("%d. %s: %s\n", i+1, s.Name, s.URL)
 		subscriptions = append(subscriptions, &s)
@@ -114,60 +159,112 @@ func querySubscriptionsInNotion() ([]*Subscription, error) {
 	return subscriptions, nil
 }
 
-func fetchPosts(subscriptions []*Subscription) error {
-	var wg sync.WaitGroup
-	wg.Add(len(subscriptions))
-
-	for _, subscription := range subscriptions {
-		go func(s *Subscription) {
-			defer wg.Done()
+// dedupePosts drops the posts the state store has already handled and restores the
+// summaries of posts interrupted before they were saved. Links the store has never
+// seen are looked up in the Post database once and recorded, so pages created
+// before the store existed are not duplicated.
+func (s *Subscription) dedupePosts(ctx context.Context, client *notionAPI.Client) []*Post {
+	var posts, unknownPosts []*Post
+	for _, post := range s.Posts {
+		item, err := store.Default.Lookup(s.ID, post.Link, post.ID, post.Title, store.ContentHash(post.Content))
+		if errors.Is(err, store.ErrNotFound) {
+			unknownPosts = append(unknownPosts, post)
+			continue
+		}
+		if err != nil {
+			log.Printf("lookup state of %s error:%v\n", post.Link, err)
+			continue
+		}
 
-			s.fetchRSSPosts()
-			filters := make([]notionAPI.DatabaseFilter, len(s.Posts))
-			for i, post := range s.Posts {
-				filters[i] = notionAPI.DatabaseFilter{
-					Property: "Link",
-					URL:      map[string]string{"equals": post.Link},
-				}
-			}
-			existPosts, err := notionAPI.FetchDatabaseItems(config.Notion.NotionPostDBID, filters, notionAPI.OR)
-			if err != nil {
-				log.Printf("query exist posts error:%v", err)
-				s.Posts = nil
-				return
+		post.stateKey = item.Key
+		if item.Status == store.StatusSaved {
+			metrics.Posts.WithLabelValues(metrics.StageDeduped).Inc()
+			continue
+		}
+		if item.Status == store.StatusFailed && item.Attempts >= config.Store.MaxAttempts {
+			metrics.Posts.WithLabelValues(metrics.StageDeduped).Inc()
+			continue
+		}
+		post.partialPageID = item.NotionPageID
+		if item.Summary != "" {
+			// Summaries stored before they were JSON fail to parse and are made again.
+			summary, err := kimi.ParseSummary(item.Summary)
+			if err == nil {
+				post.Summary = summary
 			}
+		}
+		posts = append(posts, post)
+	}
 
-			if len(existPosts) == 0 {
-				return
-			}
+	if len(unknownPosts) == 0 {
+		return posts
+	}
 
-			existPostsMap := map[string]struct{}{}
-			for _, p := range existPosts {
-				link := p.Properties["Link"].URL
-				existPostsMap[link] = struct{}{}
-			}
+	existPosts, err := queryExistPosts(ctx, client, unknownPosts)
+	if err != nil {
+		log.Printf("query exist posts error:%v", err)
+		return posts
+	}
 
-			var newPosts []*Post
-			for _, p := range s.Posts {
-				if _, exist := existPostsMap[p.Link]; exist {
-					continue
-				}
-				newPosts = append(newPosts, p)
-			}
+	for _, post := range unknownPosts {
+		item := &store.Item{
+			GUID:           post.ID,
+			Link:           post.Link,
+			ContentHash:    store.ContentHash(post.Content),
+			SubscriptionID: s.ID,
+			Title:          post.Title,
+			Status:         store.StatusPending,
+		}
+		pageID, exist := existPosts[post.Link]
+		if exist {
+			item.Status = store.StatusSaved
+			item.NotionPageID = pageID
+		}
+
+		err := store.Default.Put(item)
+		if err != nil {
+			log.Printf("save state of %s error:%v\n", post.Link, err)
+			continue
+		}
 
-			s.Posts = newPosts
-		}(subscription)
+		post.stateKey = item.Key
+		if exist {
+			metrics.Posts.WithLabelValues(metrics.StageDeduped).Inc()
+			continue
+		}
+		posts = append(posts, post)
 	}
 
-	wg.Wait()
-	return nil
+	return posts
+}
+
+// queryExistPosts returns the page id of every post whose link is already in the Post database.
+func queryExistPosts(ctx context.Context, client *notionAPI.Client, posts []*Post) (map[string]string, error) {
+	filters := make([]notionAPI.DatabaseFilter, len(posts))
+	for i, post := range posts {
+		filters[i] = notionAPI.DatabaseFilter{
+			Property: config.Properties.Post.Link,
+			URL:      map[string]string{"equals": post.Link},
+		}
+	}
+	existPosts, err := client.FetchDatabaseItems(ctx, config.Notion.NotionPostDBID, filters, notionAPI.OR)
+	if err != nil {
+		return nil, err
+	}
+
+	existPostsMap := map[string]string{}
+	for _, p := range existPosts {
+		link := p.Properties[config.Properties.Post.Link].URL
+		existPostsMap[link] = p.ID
+	}
+	return existPostsMap, nil
 }
 
-func (s *Subscription) fetchRSSPosts() {
+func (s *Subscription) fetchRSSPosts(ctx context.Context) {
 	err := retry.Do(
 		func() error {
 			fp := gofeed.NewParser()
-			feed, err := fp.ParseURL(s.URL)
+			feed, err := fp.ParseURLWithContext(s.URL, ctx)
 			if err != nil {
 				return err
 			}
@@ -175,21 +272,41 @@ func (s *Subscription) fetchRSSPosts() {
 				return nil
 			}
 
+			blogAddr := feed.Link
+			if blogAddr == "" {
+				blogAddr = s.URL
+			}
+
 			var posts []*Post
 			for _, item := range feed.Items {
-				if len(posts) >= 1 {
-					break
-				}
-
 				if item == nil {
 					continue
 				}
+				// Feeds do not all list the newest items first, so only the
+				// watermark item itself is skipped, the publish time and the
+				// state store decide about the rest.
+				if s.since.IsZero() && s.LastGUID != "" && item.GUID == s.LastGUID {
+					continue
+				}
 
 				content := item.Content
 				if content == "" {
 					content = item.Description
 				}
-				post := Post{ID: item.GUID, Title: item.Title, Link: item.Link, Content: content}
+				post := Post{
+					ID:       item.GUID,
+					Title:    item.Title,
+					Link:     item.Link,
+					Content:  content,
+					BlogAddr: blogAddr,
+					Feed:     s.Name,
+					Prompt:   s.Prompt,
+					Language: s.Language,
+					Style:    s.Style,
+
+					vocabulary: s.vocabulary,
+					profile:    s.profile,
+				}
 
 				var authors []*gofeed.Person
 				if len(item.Authors) > 0 {
@@ -211,142 +328,265 @@ func (s *Subscription) fetchRSSPosts() {
 					log.Printf("publish time %s parse error:%v\n", item.Published, err)
 				}
 				post.PublishTime = publishTime
+				if !s.isNewerThanWatermark(publishTime) {
+					continue
+				}
 
 				posts = append(posts, &post)
 			}
 
-			s.Posts = posts
+			s.Posts = s.limitPosts(posts)
 			return nil
 		},
+		retry.Context(ctx),
 		retry.Attempts(5),
 		retry.Delay(2*time.Second),
 		retry.DelayType(retry.BackOffDelay),
+		retry.OnRetry(func(n uint, err error) {
+			metrics.Retries.WithLabelValues("feed").Inc()
+		}),
 	)
 	if err != nil {
 		log.Printf("fetch posts error, rss:%s, error:%v\n", s.URL, err)
+		metrics.FeedFetches.WithLabelValues(s.Name, "error").Inc()
 		return
 	}
+	metrics.FeedFetches.WithLabelValues(s.Name, "ok").Inc()
+	metrics.Posts.WithLabelValues(metrics.StageDiscovered).Add(float64(len(s.Posts)))
 }
 
-func makeSummarize(subscriptions []*Subscription) {
-	var posts []*Post
-	for _, s := range subscriptions {
-		posts = append(posts, s.Posts...)
-	}
-	if len(posts) == 0 {
-		log.Println("Not any posts.")
-		return
-	}
+func (s *Subscription) readWatermark(prop map[string]notionAPI.Property) {
+	lastPublished, hasPublished := prop[config.Properties.RSS.LastPublished]
+	lastGUID, hasGUID := prop[config.Properties.RSS.LastGUID]
+	s.hasWatermark = hasPublished && hasGUID
 
-	log.Println("Begin to summarize posts...")
-	for _, post := range posts {
-		log.Printf("summarize post, %s: \"%s\" \n", post.Authors, post.Title)
-		err := post.summarize()
+	if lastPublished.Date != nil && lastPublished.Date.Start != "" {
+		t, err := parseDate(lastPublished.Date.Start)
 		if err != nil {
-			log.Printf("summarize post %s error:%v\n", post.Title, err)
-			continue
+			log.Printf("[%s] last published %s parse error:%v\n", s.Name, lastPublished.Date.Start, err)
 		}
+		s.LastPublished = t
+	}
+	if len(lastGUID.RichText) > 0 {
+		s.LastGUID = lastGUID.RichText[0].PlainText
 	}
 }
 
-func (s *Subscription) savePostSummaryToNotion() error {
-	if len(s.Posts) == 0 {
-		log.Printf("[%s] not any new posts", s.Name)
+// isNewerThanWatermark reports whether a post published at t has not been handled yet.
+// Posts without a publish time are left to the state store. During a
+// backfill the backfill start replaces the watermark. A post published at the
+// watermark itself counts as new, the state store drops it when it is a repeat.
+func (s *Subscription) isNewerThanWatermark(t time.Time) bool {
+	if !s.since.IsZero() {
+		return !t.Before(s.since)
+	}
+	if s.LastPublished.IsZero() || t.IsZero() {
+		return true
+	}
+	return !t.Before(s.LastPublished)
+}
+
+// limitPosts keeps at most MaxItems posts. On the first sync the newest posts win,
+// afterwards the oldest unseen posts are taken first so the rest follow in the next run.
+func (s *Subscription) limitPosts(posts []*Post) []*Post {
+	if !s.since.IsZero() || s.MaxItems <= 0 || len(posts) <= s.MaxItems {
+		return posts
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].PublishTime.After(posts[j].PublishTime)
+	})
+	if s.LastPublished.IsZero() && s.LastGUID == "" {
+		return posts[:s.MaxItems]
+	}
+	return posts[len(posts)-s.MaxItems:]
+}
+
+// advanceWatermark moves the watermark to the newest saved post that is older than
+// every failed post, so failed posts are picked up again by the next sync.
+func (s *Subscription) advanceWatermark(ctx context.Context, client *notionAPI.Client, saved, failed []*Post) error {
+	if !s.hasWatermark || len(saved) == 0 {
 		return nil
 	}
 
-	postDBID := config.Notion.NotionPostDBID
-	for _, post := range s.Posts {
-		log.Printf("[%s] save summary to notion, title:%s\n", postDBID, post.Title)
-		err := post.saveSummaryToNotion(postDBID)
-		if err != nil {
-			log.Printf("saveSummaryToNotion error, Name:%s, err:%v\n", post.Title, err)
+	var oldestFailed time.Time
+	for _, p := range failed {
+		if oldestFailed.IsZero() || p.PublishTime.Before(oldestFailed) {
+			oldestFailed = p.PublishTime
+		}
+	}
+	if len(failed) > 0 && oldestFailed.IsZero() {
+		return nil
+	}
+
+	var newest *Post
+	for _, p := range saved {
+		if !oldestFailed.IsZero() && !p.PublishTime.Before(oldestFailed) {
 			continue
 		}
+		if newest == nil || p.PublishTime.After(newest.PublishTime) {
+			newest = p
+		}
+	}
+	if newest == nil || newest.PublishTime.Before(s.LastPublished) {
+		return nil
+	}
+
+	props := map[string]notionAPI.Property{
+		config.Properties.RSS.LastGUID: {
+			RichText: []notionAPI.RichTextProperty{
+				{Text: notionAPI.TextField{Content: newest.ID}},
+			},
+		},
+	}
+	if !newest.PublishTime.IsZero() {
+		props[config.Properties.RSS.LastPublished] = notionAPI.Property{
+			Date: &notionAPI.DateProperty{Start: newest.PublishTime.Format(time.RFC3339)},
+		}
 	}
+
+	err := client.UpdatePage(ctx, s.ID, props)
+	if err != nil {
+		return err
+	}
+
+	s.LastGUID = newest.ID
+	s.LastPublished = newest.PublishTime
 	return nil
 }
 
-func (post *Post) summarize() error {
+func (post *Post) summarize(ctx context.Context, summarizer kimi.Summarizer) error {
+	art := kimi.Article{
+		Link:       post.Link,
+		Title:      post.Title,
+		Author:     post.Authors,
+		Feed:       post.Feed,
+		Text:       article.Text(ctx, post.Link, post.Content),
+		Template:   post.Prompt,
+		Language:   post.Language,
+		Style:      post.Style,
+		Vocabulary: post.vocabulary,
+		Profile:    post.profile,
+	}
+	// The article is scored once, so retrying the summary neither pays for the
+	// score again nor changes it. Without the profile an article left unscored
+	// is not scored by Summarize either.
+	if scorer, ok := summarizer.(kimi.Scorer); ok {
+		err := retry.Do(
+			func() error {
+				relevance, err := scorer.Score(ctx, art)
+				if err != nil {
+					log.Printf("score %s error:%v\n", post.Link, err)
+					return err
+				}
+				art.Relevance = relevance
+				return nil
+			},
+			aiRetryOptions(ctx)...,
+		)
+		if err != nil {
+			return err
+		}
+		art.Profile = ""
+	}
+
 	return retry.Do(
 		func() error {
-			prompt := post.Link
-
-			plainSummary, err := kimi.SendChatRequest(prompt)
+			summary, err := summarizer.Summarize(ctx, art)
 			if err != nil {
-				log.Printf("kimi error:%v\n", err)
+				log.Printf("summarizer error:%v\n", err)
 				return err
 			}
 
-			summary := parseSummary(plainSummary)
 			post.Summary = summary
 			return nil
 		},
+		aiRetryOptions(ctx)...,
+	)
+}
+
+// aiRetryOptions is the retry policy of the requests to the AI provider.
+func aiRetryOptions(ctx context.Context) []retry.Option {
+	return []retry.Option{
+		retry.Context(ctx),
+		// Invalid answers have already been asked for again by the summarizer.
+		retry.RetryIf(func(err error) bool {
+			return !errors.Is(err, kimi.ErrInvalidSummary) && !errors.Is(err, kimi.ErrArticleTooLong)
+		}),
 		retry.Attempts(5),
-		retry.Delay(2*time.Second),
+		retry.Delay(2 * time.Second),
 		retry.DelayType(retry.BackOffDelay),
-	)
+		retry.OnRetry(func(n uint, err error) {
+			metrics.Retries.WithLabelValues("ai").Inc()
+		}),
+	}
 }
 
-func (post *Post) saveSummaryToNotion(databaseID string) error {
-	summary := post.Summary
-	if summary == nil {
-		return nil
+// recordState applies fn to the post's record in the state store.
+func (post *Post) recordState(fn func(item *store.Item)) {
+	if post.stateKey == "" {
+		return
 	}
 
-	cnTitle := ""
-	outline := ""
+	err := store.Default.Update(post.stateKey, fn)
+	if err != nil {
+		log.Printf("update state of %s error:%v\n", post.Link, err)
+	}
+}
 
-	var prevBlock notionAPI.Block
-	for _, block := range post.Summary {
-		if block.Heading2 != nil {
-			cnTitle = block.Heading2.RichText[0].Text.Content
-		} else if block.Paragraph != nil {
-			content := block.Paragraph.RichText[0].Text.Content
-			if prevBlock.Object == "" {
-				if content == "概要" {
-					prevBlock = block
-				}
-			} else {
-				if content == "总结" {
-					break
-				}
-				if prevBlock.Paragraph.RichText[0].Text.Content == "概要" {
-					outline += content
-				}
-			}
-		}
+func (post *Post) saveSummaryToNotion(ctx context.Context, client *notionAPI.Client, databaseID string) (string, error) {
+	summary := post.Summary
+	if summary == nil {
+		return "", nil
 	}
 
+	postProps := config.Properties.Post
 	pageProps := map[string]notionAPI.Property{
-		"Name": {
+		postProps.Name: {
 			Title: []notionAPI.TitleProperty{
 				{Text: notionAPI.TextField{Content: post.Title}},
 			},
 		},
-		"Authors": {
+		postProps.Authors: {
 			RichText: []notionAPI.RichTextProperty{
 				{Text: notionAPI.TextField{Content: post.Authors}},
 			},
 		},
-		"CN Title": {
+		postProps.CNTitle: {
 			RichText: []notionAPI.RichTextProperty{
-				{Text: notionAPI.TextField{Content: cnTitle}},
+				{Text: notionAPI.TextField{Content: summary.Title}},
 			},
 		},
-		"Published": {
+		postProps.Published: {
 			Date: &notionAPI.DateProperty{
 				Start: post.PublishTime.Format("2006-01-02 15:04:05"),
 			},
 		},
-		"Link": {URL: post.Link},
-		"Outline": {
+		postProps.Link: {URL: post.Link},
+		postProps.Outline: {
 			RichText: []notionAPI.RichTextProperty{
-				{Text: notionAPI.TextField{Content: outline}},
+				{Text: notionAPI.TextField{Content: summary.Outline}},
 			},
 		},
 	}
 
+	if postProps.Tags != "" && len(summary.Tags) > 0 {
+		tags := make([]notionAPI.SelectProperty, len(summary.Tags))
+		for i, tag := range summary.Tags {
+			tags[i] = notionAPI.SelectProperty{Name: tag}
+		}
+		pageProps[postProps.Tags] = notionAPI.Property{MultiSelect: tags}
+	}
+	if postProps.Score != "" && summary.Relevance != nil {
+		score := float64(summary.Relevance.Score)
+		pageProps[postProps.Score] = notionAPI.Property{Number: &score}
+	}
+	if postProps.Style != "" && summary.Style != "" {
+		pageProps[postProps.Style] = notionAPI.Property{
+			Select: &notionAPI.SelectProperty{Name: summary.Style.Label()},
+		}
+	}
+
 	children := []notionAPI.Block{
 		{
 			Object:   "block",
@@ -354,74 +594,26 @@ func (post *Post) saveSummaryToNotion(databaseID string) error {
 			Bookmark: &notionAPI.BlockBookmark{URL: post.Link},
 		},
 	}
-	children = append(children, summary...)
-
-	_, err := notionAPI.CreatePageInDatabase(databaseID, pageProps, children)
-	return err
-}
+	children = append(children, summaryBlocks(summary)...)
 
-func parseSummary(plainSummary string) []notionAPI.Block {
-	var blocks []notionAPI.Block
-
-	node := blackfriday.New().Parse([]byte(plainSummary))
-	node.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
-		if entering {
-			text := ""
-			switch node.Type {
-			case blackfriday.Heading:
-				text = string(node.FirstChild.Literal)
-				level := node.HeadingData.Level
-
-				if level == 3 {
-					blocks = append(blocks, notionAPI.Block{
-						Object: "block",
-						Type:   "paragraph",
-						Paragraph: &notionAPI.BlockParagraph{
-							RichText: []notionAPI.RichTextProperty{
-								{
-									Text: notionAPI.TextField{Content: text},
-									Annotations: notionAPI.Annotations{
-										Bold:  true,
-										Color: "default",
-									},
-								},
-							},
-						},
-					})
-				}
-			case blackfriday.Paragraph:
-				text = string(node.FirstChild.Literal)
-				blocks = append(blocks, notionAPI.Block{
-					Object: "block",
-					Type:   "paragraph",
-					Paragraph: &notionAPI.BlockParagraph{
-						RichText: []notionAPI.RichTextProperty{
-							{Text: notionAPI.TextField{Content: text}},
-						},
-					},
-				})
-			}
+	if post.partialPageID != "" {
+		err := client.ArchivePage(ctx, post.partialPageID)
+		if err != nil {
+			return "", fmt.Errorf("archive partial page %s: %w", post.partialPageID, err)
 		}
-
-		return blackfriday.GoToNext
-	})
-
-	if len(blocks) == 0 {
-		return nil
+		log.Printf("archived partial page %s of %s\n", post.partialPageID, post.Link)
+		post.partialPageID = ""
+		post.recordState(func(item *store.Item) {
+			item.NotionPageID = ""
+		})
 	}
 
-	titleBlock := blocks[0]
-	blocks[0] = notionAPI.Block{
-		Object: "block",
-		Type:   "heading_2",
-		Heading2: &notionAPI.BlockHeading2{
-			RichText: []notionAPI.RichTextProperty{
-				{Text: notionAPI.TextField{Content: titleBlock.Paragraph.RichText[0].Text.Content}},
-			},
-		},
-	}
+	return client.CreatePageInDatabase(ctx, databaseID, pageProps, children)
+}
 
-	return blocks
+// summaryBlocks lays the summary out as the body of the Notion page.
+func summaryBlocks(summary *Summary) []notionAPI.Block {
+	return notionAPI.MarkdownToBlocks(summary.Markdown())
 }
 
 func parseDate(dateStr string) (time.Time, error) {
@@ -433,12 +625,13 @@ func parseDate(dateStr string) (time.Time, error) {
 		"Mon, 2 Jan 2006 15:04:05 MST", // Some feeds use a variant of RFC1123 with no leading zero on the day
 		"2006-01-02T15:04:05Z07:00",    // ISO 8601 with timezone offset
 		"2006-01-02T15:04:05Z",         // ISO 8601 UTC
+		time.RFC3339Nano,               // Notion date property with fractional seconds
+		"2006-01-02",                   // Notion date property without time
 	}
 
 	for _, layout := range layouts {
 		t, err := time.Parse(layout, dateStr)
 		if err == nil {
-			t = t.Truncate(time.Minute)
 			return t, nil
 		}
 	}
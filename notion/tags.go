@@ -0,0 +1,43 @@
+package notion
+
+import (
+	"context"
+	"log"
+	"notion-summary/config"
+	notionAPI "notion-summary/notion/api"
+	"strings"
+)
+
+// tagVocabulary returns the configured tags followed by the options of the
+// Post database's tags property when they are read from Notion. The
+// configured tags are kept when the database cannot be read.
+func tagVocabulary(ctx context.Context, client *notionAPI.Client) []string {
+	vocabulary := []string{}
+	seen := map[string]bool{}
+	add := func(tag string) {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		vocabulary = append(vocabulary, tag)
+	}
+
+	for _, tag := range config.AI.Tags {
+		add(tag)
+	}
+	if !config.AI.TagsFromNotion || config.Properties.Post.Tags == "" {
+		return vocabulary
+	}
+
+	database, err := client.RetrieveDatabase(ctx, config.Notion.NotionPostDBID)
+	if err != nil {
+		log.Printf("read tag options error:%v\n", err)
+		return vocabulary
+	}
+	for _, option := range database.Properties[config.Properties.Post.Tags].Options() {
+		add(option.Name)
+	}
+	return vocabulary
+}
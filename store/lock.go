@@ -0,0 +1,42 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var ErrLocked = errors.New("file is locked by another process")
+
+// FileLock is an advisory lock on a file, held by one process at a time.
+type FileLock struct {
+	file *os.File
+}
+
+// TryLockFile locks the file at path, creating it when needed. It does not
+// wait and returns ErrLocked when another process holds the lock.
+func TryLockFile(path string) (*FileLock, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &FileLock{file: file}, nil
+}
+
+func (l *FileLock) Unlock() error {
+	err := unlockFile(l.file)
+	return errors.Join(err, l.file.Close())
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package store
+
+import "os"
+
+// Without flock the lock only guards against overlapping runs in this process.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
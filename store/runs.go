@@ -0,0 +1,150 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+)
+
+// keepRuns is how many runs are kept, older ones are dropped when a run starts.
+const keepRuns = 200
+
+var runsBucket = []byte("runs")
+
+var ErrRunNotFound = errors.New("run not found")
+
+// Run is the record of a single sync or summarize run.
+type Run struct {
+	ID            string     `json:"id"`
+	Trigger       string     `json:"trigger"`
+	Status        RunStatus  `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Subscriptions int        `json:"subscriptions"`
+	Posts         []RunPost  `json:"posts"`
+}
+
+// RunPost is the outcome of one post handled by a run.
+type RunPost struct {
+	Subscription string `json:"subscription,omitempty"`
+	Title        string `json:"title"`
+	Link         string `json:"link"`
+	Status       Status `json:"status"`
+	NotionPageID string `json:"notion_page_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// StartRun records a new running run, its ID increases with every run.
+func (s *Store) StartRun(trigger string) (*Run, error) {
+	run := &Run{
+		Trigger:   trigger,
+		Status:    RunRunning,
+		StartedAt: time.Now(),
+		Posts:     []RunPost{},
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		run.ID = fmt.Sprint(seq)
+
+		err = putRun(bucket, run)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			count++
+		}
+		for k, _ := c.First(); k != nil && count > keepRuns; k, _ = c.First() {
+			err = c.Delete()
+			if err != nil {
+				return err
+			}
+			count--
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// FinishRun marks the run as done, failed when err is not nil.
+func (s *Store) FinishRun(run *Run, err error) error {
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = RunSucceeded
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putRun(tx.Bucket(runsBucket), run)
+	})
+}
+
+// GetRun returns the run with id, or ErrRunNotFound.
+func (s *Store) GetRun(id string) (*Run, error) {
+	var run *Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(runsBucket).Get(runKey(id))
+		if data == nil {
+			return ErrRunNotFound
+		}
+
+		run = &Run{}
+		return json.Unmarshal(data, run)
+	})
+	return run, err
+}
+
+// ListRuns returns at most limit runs, newest first.
+func (s *Store) ListRuns(limit int) ([]*Run, error) {
+	runs := []*Run{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(runsBucket).Cursor()
+		for k, v := c.Last(); k != nil && len(runs) < limit; k, v = c.Prev() {
+			run := &Run{}
+			err := json.Unmarshal(v, run)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
+		return nil
+	})
+	return runs, err
+}
+
+func putRun(bucket *bolt.Bucket, run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(runKey(run.ID), data)
+}
+
+// runKey zero pads the id so runs are stored in the order they started.
+func runKey(id string) []byte {
+	return []byte(fmt.Sprintf("%020s", id))
+}
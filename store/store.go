@@ -0,0 +1,343 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusSummarized Status = "summarized"
+	StatusSaved      Status = "saved"
+	StatusFailed     Status = "failed"
+)
+
+var (
+	itemsBucket = []byte("items")
+	// guidsBucket and hashesBucket map the GUIDs and the content hashes of a
+	// subscription to item keys. Feeds reuse plain GUIDs and boilerplate
+	// bodies, so neither is unique across subscriptions.
+	guidsBucket  = []byte("subscription_guids")
+	hashesBucket = []byte("subscription_hashes")
+)
+
+// minHashLength is the length from which content is hashed, shorter bodies
+// are too often the same boilerplate for different articles.
+const minHashLength = 200
+
+var (
+	ErrNotFound = errors.New("item not found")
+	ErrEmptyKey = errors.New("item key is empty")
+	// ErrInUse is returned by Open when another process has the store open,
+	// bbolt allows a single process per file.
+	ErrInUse = errors.New("state store is open in another process")
+)
+
+// Item is the sync history of a single feed item, keyed by its canonical link.
+type Item struct {
+	Key            string    `json:"key"`
+	GUID           string    `json:"guid,omitempty"`
+	Link           string    `json:"link"`
+	ContentHash    string    `json:"content_hash,omitempty"`
+	SubscriptionID string    `json:"subscription_id,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	Authors        string    `json:"authors,omitempty"`
+	BlogAddr       string    `json:"blog_addr,omitempty"`
+	PublishTime    time.Time `json:"publish_time,omitempty"`
+	CNTitle        string    `json:"cn_title,omitempty"`
+	Outline        string    `json:"outline,omitempty"`
+	Status         Status    `json:"status"`
+	Summary        string    `json:"summary,omitempty"`
+	NotionPageID   string    `json:"notion_page_id,omitempty"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error,omitempty"`
+	DigestPending  bool      `json:"digest_pending,omitempty"`
+	FirstSeen      time.Time `json:"first_seen"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type Store struct {
+	db *bolt.DB
+}
+
+// Default is the store opened by Init and shared by the sync job.
+var Default *Store
+
+// Init opens the store at path and makes it the Default store.
+func Init(path string) error {
+	s, err := Open(path)
+	if err != nil {
+		return err
+	}
+
+	Default = s
+	return nil
+}
+
+func Open(path string) (*Store, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s", ErrInUse, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		reindex := tx.Bucket(guidsBucket) == nil || tx.Bucket(hashesBucket) == nil
+		for _, name := range [][]byte{itemsBucket, guidsBucket, hashesBucket, runsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if reindex {
+			return reindexItems(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the item stored under key, or ErrNotFound.
+func (s *Store) Get(key string) (*Item, error) {
+	var item *Item
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		item, err = getItem(tx, key)
+		return err
+	})
+	return item, err
+}
+
+// Lookup finds an item by canonical link first, then by GUID and content hash
+// within the subscription, so the same article republished under another link
+// is still recognised. A content hash alone is not enough, the item must also
+// have the same title.
+func (s *Store) Lookup(subscriptionID, link, guid, title, contentHash string) (*Item, error) {
+	var item *Item
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		item, err = getItem(tx, CanonicalLink(link))
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		if guid != "" {
+			if key := tx.Bucket(guidsBucket).Get(indexKey(subscriptionID, guid)); key != nil {
+				item, err = getItem(tx, string(key))
+				return err
+			}
+		}
+		if contentHash != "" && title != "" {
+			if key := tx.Bucket(hashesBucket).Get(indexKey(subscriptionID, contentHash)); key != nil {
+				item, err = getItem(tx, string(key))
+				if err != nil {
+					return err
+				}
+				if strings.EqualFold(strings.TrimSpace(item.Title), strings.TrimSpace(title)) {
+					return nil
+				}
+			}
+		}
+		return ErrNotFound
+	})
+	return item, err
+}
+
+// Put inserts or replaces the item and refreshes its secondary indexes.
+func (s *Store) Put(item *Item) error {
+	if item.Key == "" {
+		item.Key = CanonicalLink(item.Link)
+	}
+	if item.Key == "" {
+		return ErrEmptyKey
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putItem(tx, item)
+	})
+}
+
+// Update loads the item under key, applies fn and writes it back in a single
+// transaction, so concurrent updates of the same item are not lost.
+func (s *Store) Update(key string, fn func(item *Item)) error {
+	if key == "" {
+		return ErrEmptyKey
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		item, err := getItem(tx, key)
+		if err != nil {
+			return err
+		}
+
+		fn(item)
+		item.Key = key
+		return putItem(tx, item)
+	})
+}
+
+func putItem(tx *bolt.Tx, item *Item) error {
+	now := time.Now()
+	if item.FirstSeen.IsZero() {
+		item.FirstSeen = now
+	}
+	item.UpdatedAt = now
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Bucket(itemsBucket).Put([]byte(item.Key), data)
+	if err != nil {
+		return err
+	}
+	return indexItem(tx, item)
+}
+
+// indexItem records the GUID and the content hash of item under its
+// subscription.
+func indexItem(tx *bolt.Tx, item *Item) error {
+	if item.GUID != "" {
+		err := tx.Bucket(guidsBucket).Put(indexKey(item.SubscriptionID, item.GUID), []byte(item.Key))
+		if err != nil {
+			return err
+		}
+	}
+	if item.ContentHash != "" {
+		err := tx.Bucket(hashesBucket).Put(indexKey(item.SubscriptionID, item.ContentHash), []byte(item.Key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reindexItems fills the GUID and content hash indexes from the stored items,
+// for a store written before they were kept per subscription, and drops the
+// old global indexes.
+func reindexItems(tx *bolt.Tx) error {
+	for _, name := range []string{"guids", "hashes"} {
+		if tx.Bucket([]byte(name)) != nil {
+			err := tx.DeleteBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Bucket(itemsBucket).ForEach(func(_, v []byte) error {
+		item := &Item{}
+		err := json.Unmarshal(v, item)
+		if err != nil {
+			return err
+		}
+		return indexItem(tx, item)
+	})
+}
+
+func indexKey(subscriptionID, value string) []byte {
+	return []byte(subscriptionID + "\x00" + value)
+}
+
+// ForEach walks every stored item in key order until fn returns an error.
+func (s *Store) ForEach(fn func(item *Item) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(itemsBucket).ForEach(func(_, v []byte) error {
+			item := &Item{}
+			err := json.Unmarshal(v, item)
+			if err != nil {
+				return err
+			}
+			return fn(item)
+		})
+	})
+}
+
+// Stats counts the stored items by status.
+func (s *Store) Stats() (map[Status]int, error) {
+	stats := map[Status]int{}
+	err := s.ForEach(func(item *Item) error {
+		stats[item.Status]++
+		return nil
+	})
+	return stats, err
+}
+
+func getItem(tx *bolt.Tx, key string) (*Item, error) {
+	data := tx.Bucket(itemsBucket).Get([]byte(key))
+	if data == nil {
+		return nil, ErrNotFound
+	}
+
+	item := &Item{}
+	err := json.Unmarshal(data, item)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// CanonicalLink normalises a link so trivial variations map to the same key:
+// the scheme and host are lower cased, the fragment, tracking parameters and
+// trailing slash are dropped.
+func CanonicalLink(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(link)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// ContentHash returns a stable hash of the item content, or "" for content
+// too short to tell articles apart.
+func ContentHash(content string) string {
+	content = strings.TrimSpace(content)
+	if utf8.RuneCountInString(content) < minHashLength {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	body := strings.Repeat("正文", minHashLength)
+	err = s.Put(&Item{
+		Link:           "https://a.example.com/posts/1",
+		GUID:           "1",
+		ContentHash:    ContentHash(body),
+		SubscriptionID: "feed-a",
+		Title:          "First post",
+		Status:         StatusSaved,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                                    string
+		subscriptionID, link, guid, title, hash string
+		found                                   bool
+	}{
+		{"same link", "feed-b", "https://A.example.com/posts/1?utm_source=rss", "", "", "", true},
+		{"same guid", "feed-a", "https://a.example.com/p/1", "1", "", "", true},
+		{"guid of another feed", "feed-b", "https://b.example.com/posts/1", "1", "", "", false},
+		{"same content and title", "feed-a", "https://a.example.com/p/1", "", "first post", ContentHash(body), true},
+		{"same content, other title", "feed-a", "https://a.example.com/posts/2", "", "Second post", ContentHash(body), false},
+		{"content of another feed", "feed-b", "https://b.example.com/posts/1", "", "First post", ContentHash(body), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Lookup(tt.subscriptionID, tt.link, tt.guid, tt.title, tt.hash)
+			if tt.found && err != nil {
+				t.Errorf("Lookup() error = %v, want the item", err)
+			}
+			if !tt.found && !errors.Is(err, ErrNotFound) {
+				t.Errorf("Lookup() error = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	if hash := ContentHash("Read more..."); hash != "" {
+		t.Errorf("ContentHash() of a short body = %q, want empty", hash)
+	}
+	long := strings.Repeat("a", minHashLength)
+	if ContentHash(long) == "" || ContentHash(long) != ContentHash("  "+long+"\n") {
+		t.Errorf("ContentHash() of a long body is empty or not stable")
+	}
+}